@@ -0,0 +1,419 @@
+// commands
+// the commands of the lotus tool
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// command is a command of the lotus tool.
+type command struct {
+	name string
+	args string
+	desc string
+	// open is set if the command needs the open table.
+	open bool
+	// create is set if the command creates a missing table; the other commands
+	// fail with ErrTableNotFound.
+	create bool
+	run func(c *cli, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"get", "key", "print the value of key", true, false, cmdGet},
+		{"put", "key value", "set the value of key", true, true, cmdPut},
+		{"del", "key", "delete key", true, false, cmdDel},
+		{"exists", "key", "print whether key exists", true, false, cmdExists},
+		{"scan", "[-prefix p] [-reverse] [-limit n] [-start k] [-end k]", "print the keys and values", true, false, cmdScan},
+		{"count", "[-prefix p]", "print the number of entries", true, false, cmdCount},
+		{"export", "[-format jsonl|csv|tsv] [-header] [-prefix p] [file]", "write the entries to file or stdout", true, false, cmdExport},
+		{"import", "[-format jsonl|csv|tsv] [-header] [-policy overwrite|skip|fail] [-batch n] [file]", "read the entries from file or stdin", true, true, cmdImport},
+		{"compact", "", "compact the value log", true, false, cmdCompact},
+		{"sync", "", "sync the table to disk", true, false, cmdSync},
+		{"backup", "[-archive] dest", "back up the table to the directory dest", true, false, cmdBackup},
+		{"restore", "src", "restore the table from a backup directory or .tar.gz archive", false, false, cmdRestore},
+		{"config", "show|save file|validate file", "show, save or validate the config", false, false, cmdConfig},
+		{"tables", "", "list the tables of the directory", false, false, cmdTables},
+		{"shell", "[dir table]", "interactive shell on the table", false, false, cmdShell},
+		{"script", "[-dry-run] [-continue] [-atomic] file|-", "apply the statements of a script file", true, false, cmdScript},
+		{"serve", "[-addr host:port] [dir]", "serve the tables of dir over http", false, false, cmdServe},
+		{"redis", "[-addr host:port] [dir]", "serve the tables of dir with the redis protocol", false, false, cmdRedis},
+		{"help", "", "print this help", false, false, cmdHelp},
+	}
+}
+
+func findCommand(name string) *command {
+
+	for i := range commands {
+		if commands[i].name == name {return &commands[i]}
+	}
+	return nil
+}
+
+// flags returns the flag set of command name.
+func (c *cli) flags(name string) *flag.FlagSet {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses the flags of fs and checks the number of arguments.
+func parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) (err error) {
+
+	err = fs.Parse(args)
+	if err != nil {return fmt.Errorf("%w: %s: %v", errUsage, fs.Name(), err)}
+	if fs.NArg() < minArgs || fs.NArg() > maxArgs {
+		cmd := findCommand(fs.Name())
+		return fmt.Errorf("%w: %s %s", errUsage, cmd.name, cmd.args)
+	}
+	return nil
+}
+
+func cmdGet(c *cli, args []string) (err error) {
+
+	fs := c.flags("get")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+
+	val, err := c.db.GetVal(fs.Arg(0))
+	if err != nil {return err}
+	fmt.Fprintln(c.stdout, val)
+	return nil
+}
+
+func cmdPut(c *cli, args []string) (err error) {
+
+	fs := c.flags("put")
+	err = parse(fs, args, 2, 2)
+	if err != nil {return err}
+	return c.db.AddEntry(fs.Arg(0), fs.Arg(1))
+}
+
+func cmdDel(c *cli, args []string) (err error) {
+
+	fs := c.flags("del")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+
+	res, err := c.db.FindKey(fs.Arg(0))
+	if err != nil {return err}
+	if !res {return fmt.Errorf("del %s: %w", fs.Arg(0), lotusLib.ErrKeyNotFound)}
+	return c.db.DelEntry(fs.Arg(0))
+}
+
+func cmdExists(c *cli, args []string) (err error) {
+
+	fs := c.flags("exists")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+
+	res, err := c.db.FindKey(fs.Arg(0))
+	if err != nil {return err}
+	fmt.Fprintln(c.stdout, res)
+	if !res {return errFalse}
+	return nil
+}
+
+// scanFlags adds the scan flags to fs and returns a function that builds the scan options.
+func scanFlags(fs *flag.FlagSet) func() []lotusLib.ScanOption {
+
+	prefix := fs.String("prefix", "", "key prefix")
+	reverse := fs.Bool("reverse", false, "reverse order")
+	limit := fs.Int("limit", 0, "maximum number of entries")
+	start := fs.String("start", "", "first key")
+	end := fs.String("end", "", "key after the last key")
+
+	// only the flags that are set override the InterOpt defaults of the table
+	return func() (opts []lotusLib.ScanOption) {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "prefix":
+				opts = append(opts, lotusLib.WithPrefix(*prefix))
+			case "reverse":
+				opts = append(opts, lotusLib.WithReverse(*reverse))
+			case "limit":
+				opts = append(opts, lotusLib.WithLimit(*limit))
+			}
+		})
+		if len(*start) > 0 || len(*end) > 0 {opts = append(opts, lotusLib.WithRange(*start, *end))}
+		return opts
+	}
+}
+
+func cmdScan(c *cli, args []string) (err error) {
+
+	fs := c.flags("scan")
+	scanOpts := scanFlags(fs)
+	err = parse(fs, args, 0, 0)
+	if err != nil {return err}
+
+	it, err := c.db.NewIterator(scanOpts()...)
+	if err != nil {return err}
+	for it.Next() {
+		fmt.Fprintf(c.stdout, "%s\t%s\n", it.Key(), it.Value())
+	}
+	return it.Close()
+}
+
+func cmdCount(c *cli, args []string) (err error) {
+
+	fs := c.flags("count")
+	scanOpts := scanFlags(fs)
+	err = parse(fs, args, 0, 0)
+	if err != nil {return err}
+
+	n, err := c.db.Count(scanOpts()...)
+	if err != nil {return err}
+	fmt.Fprintln(c.stdout, n)
+	return nil
+}
+
+// csvOpt returns the csv options of format, or ok false for jsonl.
+func csvOpt(format string, header bool) (copt lotusLib.CSVOpt, ok bool, err error) {
+
+	switch format {
+	case "jsonl":
+		return copt, false, nil
+	case "csv":
+		return lotusLib.CSVOpt{Header: header}, true, nil
+	case "tsv":
+		return lotusLib.CSVOpt{Comma: '\t', Header: header}, true, nil
+	}
+	return copt, false, fmt.Errorf("%w: unknown format %q (jsonl, csv, tsv)", errUsage, format)
+}
+
+func cmdExport(c *cli, args []string) (err error) {
+
+	fs := c.flags("export")
+	format := fs.String("format", "jsonl", "jsonl, csv or tsv")
+	header := fs.Bool("header", false, "write a csv header")
+	prefix := fs.String("prefix", "", "key prefix")
+	err = parse(fs, args, 0, 1)
+	if err != nil {return err}
+
+	copt, isCSV, err := csvOpt(*format, *header)
+	if err != nil {return err}
+
+	w := c.stdout
+	if fs.NArg() == 1 {
+		var fil *os.File
+		fil, err = os.Create(fs.Arg(0))
+		if err != nil {return err}
+		defer func() {
+			cerr := fil.Close()
+			if cerr != nil && err == nil {err = cerr}
+		}()
+		w = fil
+	}
+
+	var n int
+	if isCSV {
+		n, err = c.db.ExportCSV(w, copt, lotusLib.WithDumpPrefix(*prefix))
+	} else {
+		n, err = c.db.Export(w, lotusLib.WithDumpPrefix(*prefix))
+	}
+	if err != nil {return err}
+	if fs.NArg() == 1 {fmt.Fprintf(c.stdout, "exported %d entries\n", n)}
+	return nil
+}
+
+func cmdImport(c *cli, args []string) (err error) {
+
+	fs := c.flags("import")
+	format := fs.String("format", "jsonl", "jsonl, csv or tsv")
+	header := fs.Bool("header", false, "skip the csv header")
+	policy := fs.String("policy", "overwrite", "existing keys: overwrite, skip or fail")
+	batch := fs.Int("batch", 1000, "entries per batch")
+	err = parse(fs, args, 0, 1)
+	if err != nil {return err}
+
+	copt, isCSV, err := csvOpt(*format, *header)
+	if err != nil {return err}
+
+	pol, err := parsePolicy(*policy)
+	if err != nil {return err}
+
+	var r io.Reader = c.stdin
+	if fs.NArg() == 1 {
+		fil, err := os.Open(fs.Arg(0))
+		if err != nil {return err}
+		defer fil.Close()
+		r = fil
+	}
+
+	opts := []lotusLib.DumpOption{lotusLib.WithPolicy(pol), lotusLib.WithBatchSize(*batch)}
+	var n int
+	if isCSV {
+		n, err = c.db.ImportCSV(r, copt, opts...)
+	} else {
+		n, err = c.db.Import(r, opts...)
+	}
+	fmt.Fprintf(c.stdout, "imported %d entries\n", n)
+	return err
+}
+
+func parsePolicy(str string) (pol lotusLib.ImportPolicy, err error) {
+
+	switch str {
+	case "overwrite":
+		return lotusLib.Overwrite, nil
+	case "skip":
+		return lotusLib.SkipExisting, nil
+	case "fail":
+		return lotusLib.FailExisting, nil
+	}
+	return 0, fmt.Errorf("%w: unknown policy %q (overwrite, skip, fail)", errUsage, str)
+}
+
+func cmdCompact(c *cli, args []string) (err error) {
+
+	err = parse(c.flags("compact"), args, 0, 0)
+	if err != nil {return err}
+	return c.db.Compact()
+}
+
+func cmdSync(c *cli, args []string) (err error) {
+
+	err = parse(c.flags("sync"), args, 0, 0)
+	if err != nil {return err}
+	return c.db.Sync()
+}
+
+func cmdBackup(c *cli, args []string) (err error) {
+
+	fs := c.flags("backup")
+	archive := fs.Bool("archive", false, "write a .tar.gz archive into the directory dest")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+
+	if *archive {
+		arcPath, err := c.db.BackupArchive(fs.Arg(0))
+		if err != nil {return err}
+		fmt.Fprintf(c.stdout, "backup %s\n", arcPath)
+		return nil
+	}
+
+	man, err := c.db.Backup(fs.Arg(0))
+	if err != nil {return err}
+	fmt.Fprintf(c.stdout, "backup %s: %d entries, %d files\n", fs.Arg(0), man.Entries, len(man.Files))
+	return nil
+}
+
+func cmdRestore(c *cli, args []string) (err error) {
+
+	fs := c.flags("restore")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+	_, err = c.resolve()
+	if err != nil {return err}
+	if len(c.dir) == 0 || len(c.table) == 0 {return fmt.Errorf("%w: restore needs -dir and -table or -config", errUsage)}
+
+	src := fs.Arg(0)
+	if strings.HasSuffix(src, ".tar.gz") {return lotusLib.RestoreArchive(src, c.dir, c.table)}
+	return lotusLib.Restore(src, c.dir, c.table)
+}
+
+func cmdConfig(c *cli, args []string) (err error) {
+
+	if len(args) == 0 {return fmt.Errorf("%w: config show|save file|validate file", errUsage)}
+
+	switch args[0] {
+	case "show", "save":
+		fs := c.flags("config " + args[0])
+		maxArgs := 0
+		if args[0] == "save" {maxArgs = 1}
+		err = fs.Parse(args[1:])
+		if err != nil || fs.NArg() != maxArgs {return fmt.Errorf("%w: config show|save file", errUsage)}
+
+		// the table of the shell stays open
+		if c.db == nil {
+			err = c.open(false)
+			if err != nil {return err}
+			defer c.close()
+		}
+
+		optObj, err := c.db.LotusDbOption()
+		if err != nil {return err}
+		optData, err := yaml.Marshal(optObj)
+		if err != nil {return err}
+
+		if args[0] == "show" {
+			_, err = c.stdout.Write(optData)
+			return err
+		}
+		return os.WriteFile(fs.Arg(0), optData, 0666)
+
+	case "validate":
+		if len(args) != 2 {return fmt.Errorf("%w: config validate file", errUsage)}
+		return validateConfig(c, args[1])
+	}
+	return fmt.Errorf("%w: config show|save file|validate file", errUsage)
+}
+
+// validateConfig checks the config file without opening the table.
+func validateConfig(c *cli, filPath string) (err error) {
+
+	cl := lotusLib.ConfigLoader{
+		FilPath: filPath,
+		LookupEnv: func(string) (string, bool) {return "", false},
+	}
+	// the keys that cannot be decoded are reported with the invalid options
+	optObj, _, err := cl.Load()
+	var errs lotusLib.ConfigErrors
+	if err != nil && !errors.As(err, &errs) {return err}
+
+	err = optObj.Validate()
+	var verrs lotusLib.ConfigErrors
+	if errors.As(err, &verrs) {
+		for _, cerr := range verrs {
+			dup := false
+			for _, e := range errs {
+				if e.Field == cerr.Field {dup = true}
+			}
+			if !dup {errs = append(errs, cerr)}
+		}
+	}
+	if len(errs) > 0 {return errs}
+
+	fmt.Fprintf(c.stdout, "%s: valid\n", filPath)
+	return nil
+}
+
+func cmdTables(c *cli, args []string) (err error) {
+
+	err = parse(c.flags("tables"), args, 0, 0)
+	if err != nil {return err}
+	_, err = c.resolve()
+	if err != nil {return err}
+	if len(c.dir) == 0 {return fmt.Errorf("%w: tables needs -dir or -config", errUsage)}
+
+	list, err := lotusLib.ListTables(c.dir)
+	if err != nil {return err}
+
+	for _, info := range list {
+		fmt.Fprintf(c.stdout, "%s\t%s\t%s\n", info.Name, info.Created.Format("2006-01-02 15:04:05"), info.Desc)
+	}
+	return nil
+}
+
+func cmdHelp(c *cli, args []string) (err error) {
+	c.usage()
+	return nil
+}
@@ -0,0 +1,195 @@
+// lineedit
+// line editor with history and tab completion for the shell
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const maxHistory = 1000
+
+var errInterrupt = errors.New("interrupt")
+
+// lineEditor reads lines. On a terminal in raw mode it echoes the input and handles
+// the keys left, right, home (ctrl-a), end (ctrl-e), backspace, delete, up and down
+// for the history, tab for completion, ctrl-c and ctrl-d.
+type lineEditor struct {
+	in *bufio.Reader
+	out io.Writer
+	// raw is set if the input is a terminal in raw mode.
+	raw bool
+	history []string
+	// complete returns the completions of the first word of a line.
+	complete func(word string) []string
+}
+
+// readLine reads a line after writing prompt; only a raw terminal gets the prompt.
+func (le *lineEditor) readLine(prompt string) (line string, err error) {
+
+	if !le.raw {
+		line, err = le.in.ReadString('\n')
+		if err == io.EOF && len(line) > 0 {err = nil}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	fmt.Fprint(le.out, prompt)
+	var buf []rune
+	pos := 0
+	hidx := len(le.history)
+	edited := ""
+
+	for {
+		r, _, err := le.in.ReadRune()
+		if err != nil {return "", err}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(le.out, "\r\n")
+			line = string(buf)
+			le.addHistory(line)
+			return line, nil
+
+		case 3:
+			fmt.Fprint(le.out, "^C\r\n")
+			return "", errInterrupt
+
+		case 4:
+			if len(buf) > 0 {continue}
+			fmt.Fprint(le.out, "\r\n")
+			return "", io.EOF
+
+		case 127, 8:
+			if pos == 0 {continue}
+			buf = append(buf[:pos-1], buf[pos:]...)
+			pos--
+
+		case 1:
+			pos = 0
+
+		case 5:
+			pos = len(buf)
+
+		case '\t':
+			buf, pos = le.completeLine(prompt, buf, pos)
+
+		case 27:
+			key := le.escape()
+			switch key {
+			case 'A', 'B':
+				if hidx == len(le.history) {edited = string(buf)}
+				if key == 'A' && hidx > 0 {hidx--}
+				if key == 'B' && hidx < len(le.history) {hidx++}
+				if hidx == len(le.history) {
+					buf = []rune(edited)
+				} else {
+					buf = []rune(le.history[hidx])
+				}
+				pos = len(buf)
+			case 'C':
+				if pos < len(buf) {pos++}
+			case 'D':
+				if pos > 0 {pos--}
+			case '3':
+				if pos < len(buf) {buf = append(buf[:pos], buf[pos+1:]...)}
+			}
+
+		default:
+			if r < 32 {continue}
+			buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+			pos++
+		}
+		le.redraw(prompt, buf, pos)
+	}
+}
+
+// escape reads an escape sequence ESC [ x and returns x; ESC [ 3 ~ returns '3'.
+func (le *lineEditor) escape() rune {
+
+	r, _, err := le.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {return 0}
+	r, _, err = le.in.ReadRune()
+	if err != nil {return 0}
+	if r >= '0' && r <= '9' {
+		le.in.ReadRune()
+	}
+	return r
+}
+
+func (le *lineEditor) redraw(prompt string, buf []rune, pos int) {
+
+	fmt.Fprintf(le.out, "\r%s%s\x1b[K", prompt, string(buf))
+	if pos < len(buf) {fmt.Fprintf(le.out, "\x1b[%dD", len(buf) - pos)}
+}
+
+// completeLine completes the first word of buf at the end of the word.
+// One completion is inserted, several are listed.
+func (le *lineEditor) completeLine(prompt string, buf []rune, pos int) ([]rune, int) {
+
+	word := string(buf[:pos])
+	if le.complete == nil || strings.ContainsAny(word, " \t") {return buf, pos}
+
+	list := le.complete(word)
+	switch len(list) {
+	case 0:
+		return buf, pos
+	case 1:
+		ins := []rune(list[0][len(word):] + " ")
+		buf = append(buf[:pos], append(ins, buf[pos:]...)...)
+		return buf, pos + len(ins)
+	}
+
+	// insert the common prefix and list the completions
+	common := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(s, common) {common = common[:len(common)-1]}
+	}
+	ins := []rune(common[len(word):])
+	buf = append(buf[:pos], append(ins, buf[pos:]...)...)
+	fmt.Fprintf(le.out, "\r\n%s\r\n", strings.Join(list, "  "))
+	return buf, pos + len(ins)
+}
+
+func (le *lineEditor) addHistory(line string) {
+
+	if len(strings.TrimSpace(line)) == 0 {return}
+	if len(le.history) > 0 && le.history[len(le.history)-1] == line {return}
+	le.history = append(le.history, line)
+	if len(le.history) > maxHistory {le.history = le.history[len(le.history)-maxHistory:]}
+}
+
+// loadHistory reads the history file; a missing file is no error.
+func (le *lineEditor) loadHistory(filPath string) {
+
+	histData, err := os.ReadFile(filPath)
+	if err != nil {return}
+	for _, line := range strings.Split(string(histData), "\n") {
+		le.addHistory(line)
+	}
+}
+
+func (le *lineEditor) saveHistory(filPath string) (err error) {
+
+	if len(le.history) == 0 {return nil}
+	return os.WriteFile(filPath, []byte(strings.Join(le.history, "\n") + "\n"), 0600)
+}
+
+// completions returns the names that start with word, sorted.
+func completions(word string, names []string) (list []string) {
+
+	for _, nam := range names {
+		if strings.HasPrefix(nam, word) {list = append(list, nam)}
+	}
+	sort.Strings(list)
+	return list
+}
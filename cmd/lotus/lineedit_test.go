@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string) (le *lineEditor, out *bytes.Buffer) {
+
+	out = &bytes.Buffer{}
+	le = &lineEditor{
+		in: bufio.NewReader(strings.NewReader(input)),
+		out: out,
+		raw: true,
+		complete: func(word string) []string {return completions(word, []string{"get", "put", "scan", "stats"})},
+	}
+	return le, out
+}
+
+func TestLineEditor(t *testing.T) {
+
+	// edit keys: backspace, left, insert, ctrl-a, ctrl-e
+	le, _ := newTestEditor("gtx\x7f\x1b[De\x05 a\x01\r")
+	line, err := le.readLine("> ")
+	if err != nil || line != "get a" {t.Errorf("error -- readLine: %q %v", line, err)}
+
+	// history with up and down
+	le, _ = newTestEditor("put a 1\rget a\r\x1b[A\x1b[A\r\x1b[A\x1b[Bnew\r")
+	for _, want := range []string{"put a 1", "get a", "put a 1", "new"} {
+		line, err = le.readLine("> ")
+		if err != nil || line != want {t.Errorf("error -- readLine with history: %q %v expected %q", line, err, want)}
+	}
+	if len(le.history) != 4 {t.Errorf("error -- history: %q", le.history)}
+
+	// completion of a single and of several commands
+	le, out := newTestEditor("g\tkey\rs\t\r")
+	line, _ = le.readLine("> ")
+	if line != "get key" {t.Errorf("error -- completion: %q", line)}
+	line, _ = le.readLine("> ")
+	if line != "s" || !strings.Contains(out.String(), "scan  stats") {t.Errorf("error -- completion list: %q %q", line, out.String())}
+
+	// ctrl-c cancels the line, ctrl-d on an empty line ends the input
+	le, _ = newTestEditor("abc\x03\x04")
+	_, err = le.readLine("> ")
+	if err != errInterrupt {t.Errorf("error -- ctrl-c: %v", err)}
+	_, err = le.readLine("> ")
+	if err != io.EOF {t.Errorf("error -- ctrl-d: %v", err)}
+}
+
+func TestLineEditorPlain(t *testing.T) {
+
+	le := &lineEditor{in: bufio.NewReader(strings.NewReader("get a\r\nput b 2"))}
+	line, err := le.readLine("> ")
+	if err != nil || line != "get a" {t.Errorf("error -- readLine: %q %v", line, err)}
+	line, err = le.readLine("> ")
+	if err != nil || line != "put b 2" {t.Errorf("error -- readLine of the last line: %q %v", line, err)}
+	_, err = le.readLine("> ")
+	if err != io.EOF {t.Errorf("error -- readLine at the end: %v", err)}
+}
@@ -0,0 +1,197 @@
+// lotus
+// command line tool for lotusLib tables
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+// usage: lotus [-dir dir] [-table table] [-config file] [-dbg] command [args]
+//
+// The table is DirPath/TableName; -dir and -table default to the environment
+// variables LOTUS_DIRPATH and LOTUS_TABLENAME. With -config the table is opened
+// with the yaml config file, the LOTUS_* environment variables and -dir and -table
+// override it.
+//
+// exit codes:
+//	0 success
+//	1 error
+//	2 usage error
+//	3 key or table not found, exists is false
+//	4 invalid config or drift of the creation options
+//	5 table is locked by another process
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+const (
+	exitOK = 0
+	exitErr = 1
+	exitUsage = 2
+	exitNotFound = 3
+	exitConfig = 4
+	exitLocked = 5
+)
+
+var (
+	errUsage = errors.New("usage")
+	// errFalse ends a command with exitNotFound without an error message.
+	errFalse = errors.New("false")
+)
+
+// cli holds the global flags and the open table of a run.
+type cli struct {
+	dir string
+	table string
+	config string
+	dbg bool
+	dirSet bool
+	tableSet bool
+
+	stdin io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	db *lotusLib.DBObj
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("lotus", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.dir, "dir", os.Getenv("LOTUS_DIRPATH"), "directory of the tables (LOTUS_DIRPATH)")
+	fs.StringVar(&c.table, "table", os.Getenv("LOTUS_TABLENAME"), "table (LOTUS_TABLENAME)")
+	fs.StringVar(&c.config, "config", "", "yaml config file of the table")
+	fs.BoolVar(&c.dbg, "dbg", false, "debug output")
+	fs.Usage = func() {c.usage()}
+
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {return exitOK}
+	if err != nil {return exitUsage}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "dir" {c.dirSet = true}
+		if f.Name == "table" {c.tableSet = true}
+	})
+
+	if fs.NArg() == 0 {
+		c.usage()
+		return exitUsage
+	}
+
+	err = c.exec(fs.Args())
+	return c.exit(err)
+}
+
+// exec runs the command args[0] with the arguments args[1:].
+func (c *cli) exec(args []string) (err error) {
+
+	cmd := findCommand(args[0])
+	if cmd == nil {return fmt.Errorf("%w: unknown command %q", errUsage, args[0])}
+
+	// the shell keeps the table open for all its commands
+	if cmd.open && c.db == nil {
+		err = c.open(cmd.create)
+		if err != nil {return err}
+		defer c.close()
+	}
+	return cmd.run(c, args[1:])
+}
+
+// resolve sets dir and table from the config file, the LOTUS_* environment variables
+// and the flags; optObj is nil without -config.
+func (c *cli) resolve() (optObj *lotusLib.LotusDbOption, err error) {
+
+	if len(c.config) == 0 {return nil, nil}
+
+	cl := lotusLib.ConfigLoader{FilPath: c.config}
+	opt, _, err := cl.Load()
+	if err != nil {return nil, err}
+	if c.dirSet {opt.DirPath = c.dir}
+	if c.tableSet {opt.TabNam = c.table}
+	c.dir = opt.DirPath
+	c.table = opt.TabNam
+	return &opt, nil
+}
+
+// open opens the table, once. A missing table is created only if create is set.
+func (c *cli) open(create bool) (err error) {
+
+	if c.db != nil {return nil}
+
+	optObj, err := c.resolve()
+	if err != nil {return err}
+	if optObj == nil && (len(c.dir) == 0 || len(c.table) == 0) {return fmt.Errorf("%w: -dir and -table or -config are required", errUsage)}
+
+	// a config without DirPath or TableName is reported by OpenOption
+	if !create && len(c.dir) > 0 && len(c.table) > 0 {
+		ok, err := lotusLib.IsTable(c.dir, c.table)
+		if err != nil {return err}
+		if !ok {return fmt.Errorf("%s/%s: %w", c.dir, c.table, lotusLib.ErrTableNotFound)}
+	}
+
+	if optObj == nil {
+		c.db, err = lotusLib.InitDb(c.dir, c.table, c.dbg)
+		return err
+	}
+	c.db, err = lotusLib.OpenOption(optObj, c.dbg)
+	return err
+}
+
+func (c *cli) close() {
+
+	if c.db == nil {return}
+	err := c.db.Close()
+	if err != nil {fmt.Fprintf(c.stderr, "lotus: close: %v\n", err)}
+	c.db = nil
+}
+
+// exit prints err and returns its exit code.
+func (c *cli) exit(err error) int {
+
+	if err == nil {return exitOK}
+	if errors.Is(err, errFalse) {return exitNotFound}
+
+	var cerrs lotusLib.ConfigErrors
+	if errors.As(err, &cerrs) {
+		for _, cerr := range cerrs {
+			fmt.Fprintf(c.stderr, "lotus: %v\n", cerr)
+		}
+	} else {
+		fmt.Fprintf(c.stderr, "lotus: %v\n", err)
+	}
+
+	switch {
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, lotusLib.ErrKeyNotFound), errors.Is(err, lotusLib.ErrTableNotFound):
+		return exitNotFound
+	case errors.Is(err, lotusLib.ErrConfig), errors.Is(err, lotusLib.ErrDrift):
+		return exitConfig
+	case errors.Is(err, lotusLib.ErrDbLocked):
+		return exitLocked
+	}
+	return exitErr
+}
+
+func (c *cli) usage() {
+
+	fmt.Fprintf(c.stderr, "usage: lotus [-dir dir] [-table table] [-config file] [-dbg] command [args]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-40s %s\n", strings.TrimSpace(cmd.name + " " + cmd.args), cmd.desc)
+	}
+}
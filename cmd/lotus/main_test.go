@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// runCmd runs the lotus command line args on the table CliDat in dir.
+func runCmd(t *testing.T, dir, stdin string, args ...string) (code int, stdout, stderr string) {
+
+	var outBuf, errBuf bytes.Buffer
+	args = append([]string{"-dir", dir, "-table", "CliDat"}, args...)
+	code = run(args, strings.NewReader(stdin), &outBuf, &errBuf)
+	return code, outBuf.String(), errBuf.String()
+}
+
+// newTable creates the empty table CliDat in dir.
+func newTable(t *testing.T, dir string) {
+
+	db, err := lotusLib.InitDb(dir, "CliDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	db.Close()
+}
+
+func TestCommands(t *testing.T) {
+
+	dir := t.TempDir()
+
+	code, _, stderr := runCmd(t, dir, "", "put", "user:1", "alice")
+	if code != exitOK {t.Fatalf("error -- put: %d %s", code, stderr)}
+	runCmd(t, dir, "", "put", "user:2", "bob")
+	runCmd(t, dir, "", "put", "item:1", "pen")
+
+	code, stdout, _ := runCmd(t, dir, "", "get", "user:1")
+	if code != exitOK || stdout != "alice\n" {t.Errorf("error -- get: %d %q", code, stdout)}
+
+	code, _, stderr = runCmd(t, dir, "", "get", "user:9")
+	if code != exitNotFound || !strings.Contains(stderr, "key not found") {t.Errorf("error -- get of a missing key: %d %s", code, stderr)}
+
+	code, stdout, _ = runCmd(t, dir, "", "exists", "user:9")
+	if code != exitNotFound || stdout != "false\n" {t.Errorf("error -- exists: %d %q", code, stdout)}
+
+	code, stdout, _ = runCmd(t, dir, "", "scan", "-prefix", "user:", "-reverse", "-limit", "1")
+	if code != exitOK || stdout != "user:2\tbob\n" {t.Errorf("error -- scan: %d %q", code, stdout)}
+
+	code, stdout, _ = runCmd(t, dir, "", "count")
+	if code != exitOK || stdout != "3\n" {t.Errorf("error -- count: %d %q", code, stdout)}
+
+	code, stdout, _ = runCmd(t, dir, "", "export", "-format", "csv", "-prefix", "item:")
+	if code != exitOK || stdout != "item:1,pen\n" {t.Errorf("error -- export: %d %q", code, stdout)}
+
+	code, stdout, _ = runCmd(t, dir, "{\"key\":\"user:3\",\"value\":\"carol\"}\n", "import", "-policy", "skip")
+	if code != exitOK || stdout != "imported 1 entries\n" {t.Errorf("error -- import: %d %q", code, stdout)}
+
+	code, _, _ = runCmd(t, dir, "", "del", "user:1")
+	if code != exitOK {t.Errorf("error -- del: %d", code)}
+	code, _, _ = runCmd(t, dir, "", "del", "user:1")
+	if code != exitNotFound {t.Errorf("error -- del of a missing key: %d", code)}
+
+	for _, cmd := range []string{"sync", "compact"} {
+		code, _, stderr = runCmd(t, dir, "", cmd)
+		if code != exitOK {t.Errorf("error -- %s: %d %s", cmd, code, stderr)}
+	}
+
+	code, stdout, _ = runCmd(t, dir, "", "tables")
+	if code != exitOK || !strings.HasPrefix(stdout, "CliDat\t") {t.Errorf("error -- tables: %d %q", code, stdout)}
+	_, err := os.Stat(dir + "/" + lotusLib.CatalogFilNam)
+	if !os.IsNotExist(err) {t.Errorf("error -- tables wrote the catalog: %v", err)}
+}
+
+func TestMissingTable(t *testing.T) {
+
+	dir := t.TempDir()
+	for _, args := range [][]string{{"get", "key"}, {"scan"}, {"backup", dir + "/bck"}, {"config", "show"}} {
+		code, _, stderr := runCmd(t, dir, "", args...)
+		if code != exitNotFound || !strings.Contains(stderr, "table not found") {t.Errorf("error -- %s on a missing table: %d %s", args[0], code, stderr)}
+	}
+	_, err := os.Stat(dir + "/CliDat")
+	if !os.IsNotExist(err) {t.Errorf("error -- table created: %v", err)}
+
+	// the directory of the tables is not created
+	code, _, _ := runCmd(t, dir + "/nosuch", "", "tables")
+	if code == exitOK {t.Errorf("error -- tables of a missing directory")}
+	_, err = os.Stat(dir + "/nosuch")
+	if !os.IsNotExist(err) {t.Errorf("error -- directory created by tables: %v", err)}
+
+	// tables and restore take the directory and the table from the config
+	code, _, _ = runCmd(t, dir, "", "put", "key1", "val1")
+	if code != exitOK {t.Fatalf("error -- put: %d", code)}
+	code, _, _ = runCmd(t, dir, "", "backup", dir + "/bck")
+	if code != exitOK {t.Fatalf("error -- backup: %d", code)}
+	runCmd(t, dir, "", "put", "key1", "changed")
+
+	cfgPath := dir + "/cfg.yaml"
+	err = os.WriteFile(cfgPath, []byte("DirPath: " + dir + "\nTableName: CliDat\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	var outBuf, errBuf bytes.Buffer
+	code = run([]string{"-config", cfgPath, "tables"}, strings.NewReader(""), &outBuf, &errBuf)
+	if code != exitOK || !strings.HasPrefix(outBuf.String(), "CliDat\t") {t.Errorf("error -- tables with -config: %d %q %s", code, outBuf.String(), errBuf.String())}
+	code = run([]string{"-config", cfgPath, "restore", dir + "/bck"}, strings.NewReader(""), &outBuf, &errBuf)
+	if code != exitOK {t.Errorf("error -- restore with -config: %d %s", code, errBuf.String())}
+	_, stdout, _ := runCmd(t, dir, "", "get", "key1")
+	if stdout != "val1\n" {t.Errorf("error -- get after restore: %q", stdout)}
+}
+
+func TestBackupRestore(t *testing.T) {
+
+	dir := t.TempDir()
+	runCmd(t, dir, "", "put", "key1", "val1")
+
+	code, _, stderr := runCmd(t, dir, "", "backup", dir + "/bck")
+	if code != exitOK {t.Fatalf("error -- backup: %d %s", code, stderr)}
+	code, stdout, stderr := runCmd(t, dir, "", "backup", "-archive", dir + "/arc")
+	if code != exitOK || !strings.Contains(stdout, ".tar.gz") {t.Fatalf("error -- backup -archive: %d %s", code, stderr)}
+	arcPath := strings.TrimSpace(strings.TrimPrefix(stdout, "backup "))
+
+	runCmd(t, dir, "", "put", "key1", "changed")
+	code, _, stderr = runCmd(t, dir, "", "restore", dir + "/bck")
+	if code != exitOK {t.Fatalf("error -- restore: %d %s", code, stderr)}
+	_, stdout, _ = runCmd(t, dir, "", "get", "key1")
+	if stdout != "val1\n" {t.Errorf("error -- get after restore: %q", stdout)}
+
+	runCmd(t, dir, "", "put", "key1", "changed")
+	code, _, stderr = runCmd(t, dir, "", "restore", arcPath)
+	if code != exitOK {t.Fatalf("error -- restore of an archive: %d %s", code, stderr)}
+	_, stdout, _ = runCmd(t, dir, "", "get", "key1")
+	if stdout != "val1\n" {t.Errorf("error -- get after restore of an archive: %q", stdout)}
+}
+
+func TestConfigCommands(t *testing.T) {
+
+	dir := t.TempDir()
+	cfgPath := dir + "/cfg.yaml"
+	newTable(t, dir)
+
+	code, _, stderr := runCmd(t, dir, "", "config", "save", cfgPath)
+	if code != exitOK {t.Fatalf("error -- config save: %d %s", code, stderr)}
+	code, stdout, _ := runCmd(t, dir, "", "config", "validate", cfgPath)
+	if code != exitOK || !strings.Contains(stdout, "valid") {t.Errorf("error -- config validate: %d %q", code, stdout)}
+	code, stdout, _ = runCmd(t, dir, "", "config", "show")
+	if code != exitOK || !strings.Contains(stdout, "TableName: CliDat") {t.Errorf("error -- config show: %d %q", code, stdout)}
+
+	err := os.WriteFile(cfgPath, []byte("Partitions: 0\nIndexType: tree\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	code, _, stderr = runCmd(t, dir, "", "config", "validate", cfgPath)
+	if code != exitConfig || !strings.Contains(stderr, "Partitions") || !strings.Contains(stderr, "IndexType") {t.Errorf("error -- config validate of an invalid file: %d %s", code, stderr)}
+
+	// the keys that cannot be decoded, the invalid options and the empty DirPath are all reported
+	err = os.WriteFile(cfgPath, []byte("DirPath: \"\"\nPartitions: 0\nMemTableNumber: many\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	code, _, stderr = runCmd(t, dir, "", "config", "validate", cfgPath)
+	for _, field := range []string{"Partitions", "MemTableNumber", "DirPath"} {
+		if code != exitConfig || !strings.Contains(stderr, field) {t.Errorf("error -- config validate misses %s: %d %s", field, code, stderr)}
+	}
+}
+
+func TestScanDefaults(t *testing.T) {
+
+	dir := t.TempDir()
+	runCmd(t, dir, "", "put", "a", "1")
+	runCmd(t, dir, "", "put", "b", "2")
+
+	// the InterOpt defaults of the config apply unless a flag is set
+	cfgPath := dir + "/cfg.yaml"
+	err := os.WriteFile(cfgPath, []byte("DirPath: " + dir + "\nTableName: CliDat\nInterOpt:\n  Reverse: true\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	var outBuf, errBuf bytes.Buffer
+	code := run([]string{"-config", cfgPath, "scan"}, strings.NewReader(""), &outBuf, &errBuf)
+	if code != exitOK || outBuf.String() != "b\t2\na\t1\n" {t.Errorf("error -- scan with InterOpt.Reverse: %d %q %s", code, outBuf.String(), errBuf.String())}
+
+	outBuf.Reset()
+	code = run([]string{"-config", cfgPath, "scan", "-reverse=false", "-limit", "1"}, strings.NewReader(""), &outBuf, &errBuf)
+	if code != exitOK || outBuf.String() != "a\t1\n" {t.Errorf("error -- scan -reverse=false: %d %q %s", code, outBuf.String(), errBuf.String())}
+}
+
+func TestUsage(t *testing.T) {
+
+	code, _, _ := runCmd(t, t.TempDir(), "", "frobnicate")
+	if code != exitUsage {t.Errorf("error -- unknown command: %d", code)}
+	dir := t.TempDir()
+	newTable(t, dir)
+	code, _, _ = runCmd(t, dir, "", "get")
+	if code != exitUsage {t.Errorf("error -- get without key: %d", code)}
+
+	var outBuf, errBuf bytes.Buffer
+	code = run([]string{"get", "key"}, strings.NewReader(""), &outBuf, &errBuf)
+	if code != exitUsage {t.Errorf("error -- get without table: %d %s", code, errBuf.String())}
+}
@@ -0,0 +1,68 @@
+// parse
+// splits a command line into arguments
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitArgs splits line into arguments separated by spaces or tabs.
+// Double quotes group an argument with the escapes \" \\ \n \t;
+// single quotes group an argument literally.
+func splitArgs(line string) (args []string, err error) {
+
+	var sb strings.Builder
+	inArg := false
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+
+		case r == '\'':
+			inArg = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {end++}
+			if end == len(runes) {return nil, fmt.Errorf("unterminated ' quote")}
+			sb.WriteString(string(runes[i+1:end]))
+			i = end
+
+		case r == '"':
+			inArg = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] != '\\' || i + 1 == len(runes) {
+					sb.WriteRune(runes[i])
+					continue
+				}
+				i++
+				switch runes[i] {
+				case 'n':
+					sb.WriteRune('\n')
+				case 't':
+					sb.WriteRune('\t')
+				default:
+					sb.WriteRune(runes[i])
+				}
+			}
+			if i == len(runes) {return nil, fmt.Errorf("unterminated \" quote")}
+
+		default:
+			inArg = true
+			sb.WriteRune(r)
+		}
+	}
+	if inArg {args = append(args, sb.String())}
+	return args, nil
+}
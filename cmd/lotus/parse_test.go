@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+
+	tests := []struct {
+		line string
+		args string
+	}{
+		{"put key val", "[put key val]"},
+		{"  put\tkey   val  ", "[put key val]"},
+		{`put key "a b"`, "[put key a b]"},
+		{`put key "say \"hi\"\n"`, "[put key say \"hi\"\n]"},
+		{`put key 'a "b" \n'`, `[put key a "b" \n]`},
+		{`put key ""`, "[put key ]"},
+		{`put k"ey" v`, "[put key v]"},
+		{"", "[]"},
+	}
+	for _, tc := range tests {
+		args, err := splitArgs(tc.line)
+		if err != nil {t.Errorf("error -- splitArgs %q: %v", tc.line, err); continue}
+		if fmt.Sprint(args) != tc.args && !(len(args) == 0 && tc.args == "[]") {t.Errorf("error -- splitArgs %q: %q expected %s", tc.line, args, tc.args)}
+	}
+
+	_, err := splitArgs(`put key "val`)
+	if err == nil {t.Errorf("error -- unterminated quote")}
+	_, err = splitArgs(`put key 'val`)
+	if err == nil {t.Errorf("error -- unterminated single quote")}
+}
@@ -0,0 +1,70 @@
+// redis
+// redis protocol server for the tables of a directory
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/prr123/lotusdb/lotusLib"
+	"github.com/prr123/lotusdb/lotusLib/resp"
+)
+
+// cmdRedis serves the tables of dir, by default -dir, with the redis protocol until it is
+// interrupted. -table is the table selected by a new connection.
+func cmdRedis(c *cli, args []string) (err error) {
+
+	fs := c.flags("redis")
+	addr := fs.String("addr", "localhost:6379", "listen address")
+	err = parse(fs, args, 0, 1)
+	if err != nil {return err}
+
+	dir := c.dir
+	if fs.NArg() == 1 {dir = fs.Arg(0)}
+	if len(dir) == 0 {return fmt.Errorf("%w: redis needs dir or -dir", errUsage)}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {return err}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return c.serveRedis(ctx, ln, dir)
+}
+
+// serveRedis serves the tables of dir on ln until ctx is done.
+func (c *cli) serveRedis(ctx context.Context, ln net.Listener, dir string) (err error) {
+
+	cat, err := lotusLib.OpenCatalog(dir, c.dbg)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	defer cat.Close()
+
+	srv := resp.New(cat, c.table)
+	fmt.Fprintf(c.stdout, "serving %s with the redis protocol on %s\n", dir, ln.Addr())
+
+	errCh := make(chan error, 1)
+	go func() {errCh <- srv.Serve(ln)}()
+
+	select {
+	case err = <-errCh:
+		srv.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	srv.Close()
+	err = <-errCh
+	if err == resp.ErrServerClosed {return nil}
+	return err
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+func TestRedis(t *testing.T) {
+
+	dir := t.TempDir()
+	code, _, stderr := runCmd(t, dir, "", "put", "user:1", "alice")
+	if code != exitOK {t.Fatalf("error -- put: %d %s", code, stderr)}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {t.Fatalf("error -- Listen: %v", err)}
+
+	var outBuf bytes.Buffer
+	c := &cli{dir: dir, table: "CliDat", stdout: &outBuf, stderr: io.Discard}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {done <- c.serveRedis(ctx, ln, dir)}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {t.Fatalf("error -- Dial: %v", err)}
+	fmt.Fprintf(conn, "*2\r\n$3\r\nGET\r\n$6\r\nuser:1\r\n")
+	r := bufio.NewReader(conn)
+	header, _ := r.ReadString('\n')
+	val, _ := r.ReadString('\n')
+	if header != "$5\r\n" || val != "alice\r\n" {t.Errorf("error -- GET: %q %q", header, val)}
+	conn.Close()
+
+	cancel()
+	err = <-done
+	if err != nil {t.Errorf("error -- redis after shutdown: %v", err)}
+
+	// an unknown -table is reported at once
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {t.Fatalf("error -- Listen: %v", err)}
+	c.table = "nosuch"
+	err = c.serveRedis(context.Background(), ln, dir)
+	if !errors.Is(err, lotusLib.ErrTableNotFound) {t.Errorf("error -- redis with an unknown table: %v", err)}
+}
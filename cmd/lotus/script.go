@@ -0,0 +1,271 @@
+// script
+// apply a script of changes and assertions to a table
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+// A script has a statement per line; the arguments are split as by the shell.
+// Empty lines and lines starting with # are ignored.
+//
+//	put key value
+//	del key
+//	delprefix prefix
+//	expect key=value	the value of key is value
+//	expect key value	the same
+//	expect key		key exists
+//	expect !key		key does not exist
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// statement is a line of a script.
+type statement struct {
+	lin int
+	text string
+	op string
+	key string
+	val string
+	// hasVal is set if expect checks the value.
+	hasVal bool
+	// absent is set if expect checks that the key does not exist.
+	absent bool
+}
+
+// parseScript reads the statements of a script; it checks the whole script before any statement runs.
+func parseScript(r io.Reader) (stmts []statement, err error) {
+
+	var errList []string
+	scan := bufio.NewScanner(r)
+	scan.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lin := 1; scan.Scan(); lin++ {
+		text := strings.TrimSpace(scan.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {continue}
+
+		st, err := parseStatement(text)
+		if err != nil {
+			errList = append(errList, fmt.Sprintf("line %d: %v", lin, err))
+			continue
+		}
+		st.lin = lin
+		st.text = text
+		stmts = append(stmts, st)
+	}
+	err = scan.Err()
+	if err != nil {return nil, err}
+	if len(errList) > 0 {return nil, fmt.Errorf("%w: script:\n%s", errUsage, strings.Join(errList, "\n"))}
+	return stmts, nil
+}
+
+func parseStatement(text string) (st statement, err error) {
+
+	args, err := splitArgs(text)
+	if err != nil {return st, err}
+	st.op = args[0]
+	args = args[1:]
+
+	switch st.op {
+	case "put":
+		if len(args) != 2 {return st, fmt.Errorf("put key value")}
+		st.key, st.val = args[0], args[1]
+	case "del":
+		if len(args) != 1 {return st, fmt.Errorf("del key")}
+		st.key = args[0]
+	case "delprefix":
+		if len(args) != 1 || len(args[0]) == 0 {return st, fmt.Errorf("delprefix prefix; the prefix cannot be empty")}
+		st.key = args[0]
+	case "expect":
+		switch {
+		case len(args) == 2:
+			st.key, st.val, st.hasVal = args[0], args[1], true
+		case len(args) == 1 && strings.HasPrefix(args[0], "!"):
+			st.key, st.absent = args[0][1:], true
+		case len(args) == 1 && strings.Contains(args[0], "="):
+			st.key, st.val, _ = strings.Cut(args[0], "=")
+			st.hasVal = true
+		case len(args) == 1:
+			st.key = args[0]
+		default:
+			return st, fmt.Errorf("expect key=value|key value|key|!key")
+		}
+	default:
+		return st, fmt.Errorf("unknown statement %q (put, del, delprefix, expect)", st.op)
+	}
+	if len(st.key) == 0 {return st, fmt.Errorf("%s: empty key", st.op)}
+	return st, nil
+}
+
+// scriptTarget is the table, or a batch on it, that a script changes.
+type scriptTarget interface {
+	get(key string) (val string, ok bool, err error)
+	put(key, val string) error
+	del(key string) error
+	keys(prefix string) ([]string, error)
+}
+
+type dbTarget struct {
+	db *lotusLib.DBObj
+}
+
+func (t dbTarget) get(key string) (val string, ok bool, err error) {
+
+	val, err = t.db.GetVal(key)
+	if errors.Is(err, lotusLib.ErrKeyNotFound) {return "", false, nil}
+	return val, err == nil, err
+}
+
+func (t dbTarget) put(key, val string) error {
+	return t.db.AddEntry(key, val)
+}
+
+func (t dbTarget) del(key string) error {
+	return t.db.DelEntry(key)
+}
+
+func (t dbTarget) keys(prefix string) (keys []string, err error) {
+	keys, _, err = t.db.Scan(lotusLib.WithPrefix(prefix), lotusLib.WithReverse(false))
+	return keys, err
+}
+
+type batchTarget struct {
+	b *lotusLib.BatchObj
+}
+
+func (t batchTarget) get(key string) (val string, ok bool, err error) {
+
+	valdat, err := t.b.Get([]byte(key))
+	if errors.Is(err, lotusLib.ErrKeyNotFound) {return "", false, nil}
+	return string(valdat), err == nil, err
+}
+
+func (t batchTarget) put(key, val string) error {
+	return t.b.Put([]byte(key), []byte(val))
+}
+
+func (t batchTarget) del(key string) error {
+	return t.b.Delete([]byte(key))
+}
+
+func (t batchTarget) keys(prefix string) ([]string, error) {
+	return t.b.Keys(prefix)
+}
+
+// exec runs the statement on target and returns a description of the result.
+func (st *statement) exec(target scriptTarget) (msg string, err error) {
+
+	switch st.op {
+	case "put":
+		return "", target.put(st.key, st.val)
+
+	case "del":
+		_, ok, err := target.get(st.key)
+		if err != nil {return "", err}
+		if !ok {return "not found", nil}
+		return "", target.del(st.key)
+
+	case "delprefix":
+		keys, err := target.keys(st.key)
+		if err != nil {return "", err}
+		for _, key := range keys {
+			err = target.del(key)
+			if err != nil {return "", err}
+		}
+		return fmt.Sprintf("%d keys", len(keys)), nil
+
+	case "expect":
+		val, ok, err := target.get(st.key)
+		if err != nil {return "", err}
+		switch {
+		case st.absent && ok:
+			return "", fmt.Errorf("key exists")
+		case st.absent:
+			return "", nil
+		case !ok:
+			return "", fmt.Errorf("key not found")
+		case st.hasVal && val != st.val:
+			return "", fmt.Errorf("value is %q", val)
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown statement %q", st.op)
+}
+
+// cmdScript applies a script. Without -atomic every statement is written on its own;
+// -atomic writes all statements in one batch that is only committed if none fails.
+// -dry-run runs the statements on a batch that is rolled back. -continue runs the
+// statements after a failure.
+func cmdScript(c *cli, args []string) (err error) {
+
+	fs := c.flags("script")
+	dryRun := fs.Bool("dry-run", false, "check the statements without writing")
+	cont := fs.Bool("continue", false, "continue after a failed statement")
+	atomic := fs.Bool("atomic", false, "write all statements in one batch")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+
+	var r io.Reader = c.stdin
+	if fs.Arg(0) != "-" {
+		fil, err := os.Open(fs.Arg(0))
+		if err != nil {return err}
+		defer fil.Close()
+		r = fil
+	}
+
+	stmts, err := parseScript(r)
+	if err != nil {return err}
+
+	var batch *lotusLib.BatchObj
+	var target scriptTarget = dbTarget{db: c.db}
+	if *dryRun || *atomic {
+		batch = c.db.NewBatch()
+		target = batchTarget{b: batch}
+	}
+
+	label := "ok  "
+	if *dryRun {label = "dry "}
+
+	ok, failed := 0, 0
+	for i := range stmts {
+		st := &stmts[i]
+		msg, err := st.exec(target)
+		if err != nil {
+			failed++
+			fmt.Fprintf(c.stdout, "FAIL line %d: %s: %v\n", st.lin, st.text, err)
+			if !*cont {break}
+			continue
+		}
+		ok++
+		if len(msg) > 0 {msg = " (" + msg + ")"}
+		fmt.Fprintf(c.stdout, "%s line %d: %s%s\n", label, st.lin, st.text, msg)
+	}
+
+	skipped := len(stmts) - ok - failed
+	fmt.Fprintf(c.stdout, "%d statements: %d ok, %d failed, %d skipped\n", len(stmts), ok, failed, skipped)
+
+	if batch != nil {
+		switch {
+		case *dryRun:
+			batch.Rollback()
+			fmt.Fprintf(c.stdout, "dry run: no changes written\n")
+		case failed > 0:
+			batch.Rollback()
+			fmt.Fprintf(c.stdout, "atomic: rolled back, no changes written\n")
+		default:
+			err = batch.Commit()
+			if err != nil {return err}
+			fmt.Fprintf(c.stdout, "atomic: committed\n")
+		}
+	}
+
+	if failed > 0 {return fmt.Errorf("script: %d statements failed", failed)}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const testScript = `# reviewed changes
+put user:1 alice
+put user:2 "bob smith"
+put tmp:1 x
+put tmp:2 y
+delprefix tmp:
+expect user:2="bob smith"
+expect !tmp:1
+expect user:1 carol
+del user:9
+put user:3 dave
+`
+
+func TestScript(t *testing.T) {
+
+	dir := t.TempDir()
+	newTable(t, dir)
+	scrPath := dir + "/changes.txt"
+	err := os.WriteFile(scrPath, []byte(testScript), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	// dry run writes nothing
+	code, stdout, _ := runCmd(t, dir, "", "script", "-dry-run", "-continue", scrPath)
+	if code != exitErr || !strings.Contains(stdout, "FAIL line 9: expect user:1 carol: value is \"alice\"") {t.Errorf("error -- dry run: %d\n%s", code, stdout)}
+	if !strings.Contains(stdout, "dry  line 6: delprefix tmp: (2 keys)") || !strings.Contains(stdout, "10 statements: 9 ok, 1 failed, 0 skipped") {t.Errorf("error -- dry run output:\n%s", stdout)}
+	_, stdout, _ = runCmd(t, dir, "", "count")
+	if stdout != "0\n" {t.Errorf("error -- dry run wrote entries: %s", stdout)}
+
+	// atomic rolls back on a failure
+	code, stdout, _ = runCmd(t, dir, "", "script", "-atomic", scrPath)
+	if code != exitErr || !strings.Contains(stdout, "7 ok, 1 failed, 2 skipped") || !strings.Contains(stdout, "rolled back") {t.Errorf("error -- atomic: %d\n%s", code, stdout)}
+	_, stdout, _ = runCmd(t, dir, "", "count")
+	if stdout != "0\n" {t.Errorf("error -- atomic script with a failure wrote entries: %s", stdout)}
+
+	// stop on the first failure without batch
+	code, stdout, _ = runCmd(t, dir, "", "script", scrPath)
+	if code != exitErr {t.Errorf("error -- script: %d\n%s", code, stdout)}
+	_, stdout, _ = runCmd(t, dir, "", "scan")
+	if stdout != "user:1\talice\nuser:2\tbob smith\n" {t.Errorf("error -- entries after a stopped script: %q", stdout)}
+
+	// a script without failures from stdin
+	fixed := strings.Replace(testScript, "expect user:1 carol", "expect user:1", 1)
+	code, stdout, _ = runCmd(t, dir, fixed, "script", "-atomic", "-")
+	if code != exitOK || !strings.Contains(stdout, "atomic: committed") || !strings.Contains(stdout, "del user:9 (not found)") {t.Errorf("error -- atomic script: %d\n%s", code, stdout)}
+	_, stdout, _ = runCmd(t, dir, "", "get", "user:3")
+	if stdout != "dave\n" {t.Errorf("error -- get after the script: %q", stdout)}
+}
+
+func TestScriptSyntax(t *testing.T) {
+
+	dir := t.TempDir()
+	newTable(t, dir)
+	code, _, stderr := runCmd(t, dir, "put a\nfrob b\ndelprefix \"\"\nput ok 1\n", "script", "-")
+	if code != exitUsage {t.Errorf("error -- syntax errors: %d", code)}
+	for _, want := range []string{"line 1: put key value", "line 2: unknown statement", "line 3: delprefix"} {
+		if !strings.Contains(stderr, want) {t.Errorf("error -- syntax error output misses %q:\n%s", want, stderr)}
+	}
+}
@@ -0,0 +1,72 @@
+// serve
+// http rest server for the tables of a directory
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prr123/lotusdb/lotusLib"
+	"github.com/prr123/lotusdb/lotusLib/server"
+)
+
+// cmdServe serves the tables of dir, by default -dir, until it is interrupted.
+func cmdServe(c *cli, args []string) (err error) {
+
+	fs := c.flags("serve")
+	addr := fs.String("addr", "localhost:8080", "listen address")
+	err = parse(fs, args, 0, 1)
+	if err != nil {return err}
+
+	dir := c.dir
+	if fs.NArg() == 1 {dir = fs.Arg(0)}
+	if len(dir) == 0 {return fmt.Errorf("%w: serve needs dir or -dir", errUsage)}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {return err}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return c.serve(ctx, ln, dir)
+}
+
+// serve serves the tables of dir on ln until ctx is done.
+func (c *cli) serve(ctx context.Context, ln net.Listener, dir string) (err error) {
+
+	cat, err := lotusLib.OpenCatalog(dir, c.dbg)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	defer cat.Close()
+
+	hs := &http.Server{
+		Handler: server.New(cat),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(c.stdout, "serving %s on http://%s\n", dir, ln.Addr())
+
+	errCh := make(chan error, 1)
+	go func() {errCh <- hs.Serve(ln)}()
+
+	select {
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	return hs.Shutdown(sctx)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+
+	dir := t.TempDir()
+	code, _, stderr := runCmd(t, dir, "", "put", "user:1", "alice")
+	if code != exitOK {t.Fatalf("error -- put: %d %s", code, stderr)}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {t.Fatalf("error -- Listen: %v", err)}
+
+	var outBuf bytes.Buffer
+	c := &cli{dir: dir, stdout: &outBuf, stderr: io.Discard}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {done <- c.serve(ctx, ln, dir)}()
+
+	res, err := http.Get("http://" + ln.Addr().String() + "/tables/CliDat/keys/user:1")
+	if err != nil {t.Fatalf("error -- GET: %v", err)}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "alice" {t.Errorf("error -- GET: %d %q", res.StatusCode, body)}
+
+	cancel()
+	err = <-done
+	if err != nil {t.Errorf("error -- serve after shutdown: %v", err)}
+}
@@ -0,0 +1,302 @@
+// shell
+// interactive shell on a table
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+const defaultPage = 20
+
+// shell runs the commands of the lines read by its line editor on the open table.
+type shell struct {
+	c *cli
+	le *lineEditor
+	// mode is the value display: utf8, hex or json.
+	mode string
+	// page is the number of scan entries per page; 0 does not page.
+	page int
+}
+
+// shellCmd is a command of the shell; other commands run as lotus commands.
+type shellCmd struct {
+	name string
+	args string
+	desc string
+	run func(sh *shell, args []string) error
+}
+
+var shellCmds []shellCmd
+
+func init() {
+	shellCmds = []shellCmd{
+		{"get", "key", "print the value of key", shGet},
+		{"scan", "[-prefix p] [-reverse] [-limit n] [-start k] [-end k]", "print the keys and values a page at a time", shScan},
+		{"count", "[-prefix p]", "print the number of entries", shCount},
+		{"stats", "", "print the number and size of the entries and the table options", shStats},
+		{"mode", "utf8|hex|json", "set the value display", shMode},
+		{"page", "n", "set the scan entries per page; 0 does not page", shPage},
+		{"history", "", "print the command history", shHistory},
+		{"help", "", "print this help", shHelp},
+		{"quit", "", "leave the shell", nil},
+		{"exit", "", "leave the shell", nil},
+	}
+}
+
+// shellExcluded are the lotus commands that cannot run on the open table.
+var shellExcluded = map[string]bool{"restore": true, "shell": true, "serve": true, "redis": true}
+
+func findShellCmd(name string) *shellCmd {
+
+	for i := range shellCmds {
+		if shellCmds[i].name == name {return &shellCmds[i]}
+	}
+	return nil
+}
+
+// commandNames returns the names of the shell and lotus commands.
+func commandNames() (names []string) {
+
+	seen := make(map[string]bool)
+	for _, cmd := range shellCmds {
+		seen[cmd.name] = true
+		names = append(names, cmd.name)
+	}
+	for _, cmd := range commands {
+		if seen[cmd.name] || shellExcluded[cmd.name] {continue}
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+// cmdShell runs the shell on the table, given as dir and table or by the global flags.
+func cmdShell(c *cli, args []string) (err error) {
+
+	fs := c.flags("shell")
+	err = parse(fs, args, 0, 2)
+	if err != nil {return err}
+	switch fs.NArg() {
+	case 1:
+		return fmt.Errorf("%w: shell [dir table]", errUsage)
+	case 2:
+		c.dir = fs.Arg(0)
+		c.table = fs.Arg(1)
+		c.dirSet = true
+		c.tableSet = true
+	}
+
+	err = c.open(false)
+	if err != nil {return err}
+	defer c.close()
+
+	sh := &shell{
+		c: c,
+		le: &lineEditor{in: bufio.NewReader(c.stdin), out: c.stdout},
+		mode: "utf8",
+	}
+	sh.le.complete = func(word string) []string {return completions(word, commandNames())}
+
+	fil, ok := c.stdin.(*os.File)
+	if ok {
+		restore, err := makeRaw(int(fil.Fd()))
+		if err == nil {
+			defer restore()
+			sh.le.raw = true
+			sh.page = defaultPage
+			histPath := historyPath()
+			if len(histPath) > 0 {
+				sh.le.loadHistory(histPath)
+				defer sh.le.saveHistory(histPath)
+			}
+		}
+	}
+
+	if sh.le.raw {fmt.Fprintf(c.stdout, "lotus shell %s/%s; help lists the commands\n", c.dir, c.table)}
+	return sh.loop()
+}
+
+func historyPath() string {
+
+	home, err := os.UserHomeDir()
+	if err != nil {return ""}
+	return home + "/.lotus_history"
+}
+
+// loop runs the lines until quit or the end of the input.
+func (sh *shell) loop() (err error) {
+
+	prompt := sh.c.table + "> "
+	for {
+		line, err := sh.le.readLine(prompt)
+		if err == errInterrupt {continue}
+		if err == io.EOF {return nil}
+		if err != nil {return err}
+
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintf(sh.c.stdout, "error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {continue}
+		if args[0] == "quit" || args[0] == "exit" {return nil}
+
+		err = sh.exec(args)
+		if err != nil && !errors.Is(err, errFalse) {fmt.Fprintf(sh.c.stdout, "error: %v\n", err)}
+	}
+}
+
+// exec runs a shell command or, if there is none, the lotus command.
+func (sh *shell) exec(args []string) (err error) {
+
+	cmd := findShellCmd(args[0])
+	if cmd != nil {return cmd.run(sh, args[1:])}
+	if shellExcluded[args[0]] {return fmt.Errorf("%s is not available in the shell", args[0])}
+	return sh.c.exec(args)
+}
+
+// format returns val in the display mode.
+func (sh *shell) format(val string) string {
+
+	switch sh.mode {
+	case "hex":
+		return hex.EncodeToString([]byte(val))
+	case "json":
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(val), "", "  ") == nil {return buf.String()}
+	}
+	if utf8.ValidString(val) {return val}
+	return strconv.Quote(val)
+}
+
+func shGet(sh *shell, args []string) (err error) {
+
+	fs := sh.c.flags("get")
+	err = parse(fs, args, 1, 1)
+	if err != nil {return err}
+
+	val, err := sh.c.db.GetVal(fs.Arg(0))
+	if err != nil {return err}
+	fmt.Fprintln(sh.c.stdout, sh.format(val))
+	return nil
+}
+
+func shScan(sh *shell, args []string) (err error) {
+
+	fs := sh.c.flags("scan")
+	scanOpts := scanFlags(fs)
+	err = parse(fs, args, 0, 0)
+	if err != nil {return err}
+
+	it, err := sh.c.db.NewIterator(scanOpts()...)
+	if err != nil {return err}
+
+	n := 0
+	for it.Next() {
+		if sh.page > 0 && n > 0 && n % sh.page == 0 {
+			ans, err := sh.le.readLine("-- more (enter), q to stop -- ")
+			if err != nil || strings.TrimSpace(ans) == "q" {break}
+		}
+		fmt.Fprintf(sh.c.stdout, "%s\t%s\n", it.Key(), sh.format(it.Value()))
+		n++
+	}
+	err = it.Close()
+	if err != nil {return err}
+	fmt.Fprintf(sh.c.stdout, "(%d entries)\n", n)
+	return nil
+}
+
+func shCount(sh *shell, args []string) (err error) {
+	return cmdCount(sh.c, args)
+}
+
+func shStats(sh *shell, args []string) (err error) {
+
+	err = parse(sh.c.flags("stats"), args, 0, 0)
+	if err != nil {return err}
+
+	db := sh.c.db
+	it, err := db.NewIterator(lotusLib.WithPrefix(""), lotusLib.WithReverse(false))
+	if err != nil {return err}
+	n, keySize, valSize := 0, 0, 0
+	for it.Next() {
+		n++
+		keySize += len(it.Key())
+		valSize += len(it.Value())
+	}
+	err = it.Close()
+	if err != nil {return err}
+
+	optObj, err := db.LotusDbOption()
+	if err != nil {return err}
+
+	out := sh.c.stdout
+	fmt.Fprintf(out, "table:        %s/%s\n", db.DirPath, db.TabNam)
+	fmt.Fprintf(out, "entries:      %d\n", n)
+	fmt.Fprintf(out, "key bytes:    %s\n", lotusLib.Size(keySize))
+	fmt.Fprintf(out, "value bytes:  %s\n", lotusLib.Size(valSize))
+	fmt.Fprintf(out, "partitions:   %d\n", optObj.PartitionNum)
+	fmt.Fprintf(out, "index:        %s\n", optObj.IndexType)
+	fmt.Fprintf(out, "memtable:     %d x %s\n", optObj.MemtableNums, optObj.MemtableSize)
+	return nil
+}
+
+func shMode(sh *shell, args []string) (err error) {
+
+	if len(args) == 0 {
+		fmt.Fprintln(sh.c.stdout, sh.mode)
+		return nil
+	}
+	switch args[0] {
+	case "utf8", "hex", "json":
+		sh.mode = args[0]
+		return nil
+	}
+	return fmt.Errorf("%w: mode utf8|hex|json", errUsage)
+}
+
+func shPage(sh *shell, args []string) (err error) {
+
+	if len(args) != 1 {return fmt.Errorf("%w: page n", errUsage)}
+	page, err := strconv.Atoi(args[0])
+	if err != nil || page < 0 {return fmt.Errorf("%w: page n", errUsage)}
+	sh.page = page
+	return nil
+}
+
+func shHistory(sh *shell, args []string) (err error) {
+
+	for i, line := range sh.le.history {
+		fmt.Fprintf(sh.c.stdout, "%4d  %s\n", i + 1, line)
+	}
+	return nil
+}
+
+func shHelp(sh *shell, args []string) (err error) {
+
+	out := sh.c.stdout
+	for _, cmd := range shellCmds {
+		fmt.Fprintf(out, "  %-40s %s\n", strings.TrimSpace(cmd.name + " " + cmd.args), cmd.desc)
+	}
+	for _, cmd := range commands {
+		if findShellCmd(cmd.name) != nil || shellExcluded[cmd.name] {continue}
+		fmt.Fprintf(out, "  %-40s %s\n", strings.TrimSpace(cmd.name + " " + cmd.args), cmd.desc)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShell(t *testing.T) {
+
+	dir := t.TempDir()
+	newTable(t, dir)
+	script := strings.Join([]string{
+		"put user:1 alice",
+		`put user:2 "{\"name\":\"bob\"}"`,
+		"put user:3 carol",
+		"get user:1",
+		"mode json",
+		"get user:2",
+		"mode hex",
+		"get user:1",
+		"mode utf8",
+		"exists user:9",
+		"count -prefix user:",
+		"page 2",
+		"scan -prefix user:",
+		"",
+		"get user:9",
+		"restore x",
+		"serve",
+		"redis",
+		"stats",
+		"quit",
+		"get user:1",
+	}, "\n")
+
+	code, stdout, stderr := runCmd(t, dir, script, "shell")
+	if code != exitOK {t.Fatalf("error -- shell: %d %s", code, stderr)}
+
+	for _, want := range []string{
+		"alice\n",
+		"{\n  \"name\": \"bob\"\n}\n",
+		"616c696365\n",
+		"false\n",
+		"3\n",
+		"user:1\talice\nuser:2\t{\"name\":\"bob\"}\nuser:3\tcarol\n(3 entries)\n",
+		"error: Get: key not found\n",
+		"error: restore is not available in the shell",
+		"error: serve is not available in the shell",
+		"error: redis is not available in the shell",
+		"entries:      3\n",
+	} {
+		if !strings.Contains(stdout, want) {t.Errorf("error -- shell output misses %q:\n%s", want, stdout)}
+	}
+	if strings.Count(stdout, "alice\n") != 2 {t.Errorf("error -- command after quit ran:\n%s", stdout)}
+
+	// scan stops at the more prompt
+	code, stdout, _ = runCmd(t, dir, "page 1\nscan\nq\n", "shell", dir, "CliDat")
+	if code != exitOK || !strings.Contains(stdout, "user:1\talice\n(1 entries)") {t.Errorf("error -- scan paging: %d\n%s", code, stdout)}
+}
+
+func TestShellKeepsTable(t *testing.T) {
+
+	dir := t.TempDir()
+	runCmd(t, dir, "", "put", "user:1", "alice")
+
+	// config show runs on the table of the shell and must not close it
+	code, stdout, stderr := runCmd(t, dir, "config show\nget user:1\nscan\nstats\n", "shell")
+	if code != exitOK {t.Fatalf("error -- shell: %d %s", code, stderr)}
+	if !strings.Contains(stdout, "TableName: CliDat") || !strings.Contains(stdout, "alice\n") || !strings.Contains(stdout, "entries:      1\n") {t.Errorf("error -- shell after config show:\n%s", stdout)}
+}
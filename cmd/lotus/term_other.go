@@ -0,0 +1,18 @@
+// term_other
+// no raw terminal mode outside linux; the shell reads plain lines
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+)
+
+func makeRaw(fd int) (restore func(), err error) {
+	return nil, errors.New("raw terminal mode is not supported")
+}
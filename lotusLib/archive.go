@@ -0,0 +1,251 @@
+// archive
+// backups as tar.gz archives with a retention policy
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveTimeFormat is the time in the name of an archive TabNam-<time>.tar.gz.
+const archiveTimeFormat = "20060102T150405.000Z"
+
+const archiveExt = ".tar.gz"
+
+// ArchiveInfo describes a backup archive.
+type ArchiveInfo struct {
+	Path string
+	TabNam string
+	Created time.Time
+}
+
+// ArchiveName returns the file name of the archive of table tabNam created at tim.
+func ArchiveName(tabNam string, tim time.Time) string {
+	return tabNam + "-" + tim.UTC().Format(archiveTimeFormat) + archiveExt
+}
+
+// BackupArchive writes a backup of the table as the archive bckDir/TabNam-<time>.tar.gz.
+// The archive holds the directory TabNam with the table files, the effective config,
+// the table manifest and the backup manifest; see Backup.
+func (dbp *DBObj) BackupArchive (bckDir string) (arcPath string, err error){
+
+	err = os.MkdirAll(bckDir, 0755)
+	if err != nil {return "", fmt.Errorf("BackupArchive: %w", err)}
+
+	tmpDir, err := os.MkdirTemp(bckDir, ".backup")
+	if err != nil {return "", fmt.Errorf("BackupArchive: %w", err)}
+	defer os.RemoveAll(tmpDir)
+
+	man, err := dbp.Backup(tmpDir + "/" + dbp.TabNam)
+	if err != nil {return "", err}
+
+	arcPath = bckDir + "/" + ArchiveName(dbp.TabNam, man.Created)
+	tmpPath := arcPath + ".tmp"
+	err = writeArchive(tmpPath, tmpDir, dbp.TabNam)
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("BackupArchive: %w", err)
+	}
+
+	err = os.Rename(tmpPath, arcPath)
+	if err != nil {return "", fmt.Errorf("BackupArchive: %w", err)}
+	return arcPath, nil
+}
+
+// writeArchive writes the directory baseDir/name as tar.gz file arcPath.
+func writeArchive(arcPath, baseDir, name string) (err error){
+
+	fil, err := os.Create(arcPath)
+	if err != nil {return err}
+	defer fil.Close()
+
+	gzw := gzip.NewWriter(fil)
+	tw := tar.NewWriter(gzw)
+
+	err = filepath.WalkDir(baseDir + "/" + name, func(path string, d os.DirEntry, err error) error {
+		if err != nil {return err}
+		rel, err := filepath.Rel(baseDir, path)
+		if err != nil {return err}
+		fi, err := d.Info()
+		if err != nil {return err}
+
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {return err}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {hdr.Name += "/"}
+		err = tw.WriteHeader(hdr)
+		if err != nil || d.IsDir() {return err}
+
+		in, err := os.Open(path)
+		if err != nil {return err}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {return err}
+
+	err = tw.Close()
+	if err != nil {return err}
+	err = gzw.Close()
+	if err != nil {return err}
+	return fil.Sync()
+}
+
+// RestoreArchive replaces the table tabNam in dirPath with the backup in the archive arcPath.
+// The checksums are verified as by Restore. The table must be closed.
+func RestoreArchive(arcPath, dirPath, tabNam string) (err error){
+
+	err = os.MkdirAll(dirPath, 0755)
+	if err != nil {return fmt.Errorf("RestoreArchive: %w", err)}
+
+	tmpDir, err := os.MkdirTemp(dirPath, ".restore")
+	if err != nil {return fmt.Errorf("RestoreArchive: %w", err)}
+	defer os.RemoveAll(tmpDir)
+
+	arcNam, err := readArchive(arcPath, tmpDir)
+	if err != nil {return fmt.Errorf("RestoreArchive %s: %w", arcPath, err)}
+
+	return Restore(tmpDir + "/" + arcNam, dirPath, tabNam)
+}
+
+// readArchive extracts the archive arcPath into dir and returns the name of its top directory.
+func readArchive(arcPath, dir string) (name string, err error){
+
+	fil, err := os.Open(arcPath)
+	if err != nil {return "", err}
+	defer fil.Close()
+
+	gzr, err := gzip.NewReader(fil)
+	if err != nil {return "", err}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {break}
+		if err != nil {return "", err}
+
+		relPath := filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/"))
+		if !filepath.IsLocal(relPath) {return "", fmt.Errorf("invalid file name %q", hdr.Name)}
+		top := strings.Split(filepath.ToSlash(relPath), "/")[0]
+		if len(name) == 0 {name = top}
+		if top != name {return "", fmt.Errorf("file %q is not in %s", hdr.Name, name)}
+
+		target := filepath.Join(dir, relPath)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = extractFile(tr, target)
+		default:
+			err = fmt.Errorf("file %q: unsupported type", hdr.Name)
+		}
+		if err != nil {return "", err}
+	}
+	if len(name) == 0 {return "", fmt.Errorf("empty archive")}
+	return name, nil
+}
+
+func extractFile(r io.Reader, target string) (err error){
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {return err}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {return err}
+
+	_, err = io.Copy(out, r)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ListArchives returns the archives of table tabNam in bckDir, newest first.
+func ListArchives(bckDir, tabNam string) (list []ArchiveInfo, err error){
+
+	entries, err := os.ReadDir(bckDir)
+	if err != nil {return nil, fmt.Errorf("ListArchives: %w", err)}
+
+	prefix := tabNam + "-"
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, archiveExt) {continue}
+		timStr := strings.TrimSuffix(strings.TrimPrefix(name, prefix), archiveExt)
+		tim, err := time.Parse(archiveTimeFormat, timStr)
+		if err != nil {continue}
+		list = append(list, ArchiveInfo{Path: bckDir + "/" + name, TabNam: tabNam, Created: tim})
+	}
+	sort.Slice(list, func(i, j int) bool {return list[i].Created.After(list[j].Created)})
+	return list, nil
+}
+
+// Retention selects the archives that PruneArchives keeps.
+// An archive is kept if any rule keeps it.
+type Retention struct {
+	// KeepLast keeps the newest KeepLast archives.
+	KeepLast int
+	// KeepDaily keeps the newest archive of each of the newest KeepDaily days with an archive.
+	KeepDaily int
+	// KeepWeekly keeps the newest archive of each of the newest KeepWeekly ISO weeks with an archive.
+	KeepWeekly int
+}
+
+// Keep returns the archives of list, sorted newest first, that the retention keeps.
+func (ret Retention) Keep(list []ArchiveInfo) (keep map[string]bool) {
+
+	keep = make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for i, arc := range list {
+		if i < ret.KeepLast {keep[arc.Path] = true}
+
+		day := arc.Created.UTC().Format("2006-01-02")
+		if !days[day] && len(days) < ret.KeepDaily {
+			days[day] = true
+			keep[arc.Path] = true
+		}
+
+		year, week := arc.Created.UTC().ISOWeek()
+		weekStr := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[weekStr] && len(weeks) < ret.KeepWeekly {
+			weeks[weekStr] = true
+			keep[arc.Path] = true
+		}
+	}
+	return keep
+}
+
+// PruneArchives removes the archives of table tabNam in bckDir that the retention does not keep.
+// It returns the removed archives.
+func PruneArchives(bckDir, tabNam string, ret Retention) (removed []string, err error){
+
+	if ret.KeepLast <= 0 && ret.KeepDaily <= 0 && ret.KeepWeekly <= 0 {
+		return nil, &ConfigError{Field: "Retention", Reason: "keeps no archive"}
+	}
+
+	list, err := ListArchives(bckDir, tabNam)
+	if err != nil {return nil, err}
+
+	keep := ret.Keep(list)
+	for _, arc := range list {
+		if keep[arc.Path] {continue}
+		err = os.Remove(arc.Path)
+		if err != nil {return removed, fmt.Errorf("PruneArchives: %w", err)}
+		removed = append(removed, arc.Path)
+	}
+	return removed, nil
+}
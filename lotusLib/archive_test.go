@@ -0,0 +1,67 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBackupArchive(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "ArcDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+
+	keys, vals, err := db.FillRan(10)
+	if err != nil {t.Fatalf("error -- FillRan: %v", err)}
+
+	bckDir := dir + "/backups"
+	arcPath, err := db.BackupArchive(bckDir)
+	if err != nil {t.Fatalf("error -- BackupArchive: %v", err)}
+
+	list, err := ListArchives(bckDir, "ArcDat")
+	if err != nil || len(list) != 1 || list[0].Path != arcPath {t.Errorf("error -- ListArchives: %+v %v", list, err)}
+
+	err = db.DelEntry(keys[3])
+	if err != nil {t.Errorf("error -- DelEntry: %v", err)}
+	db.Close()
+
+	err = RestoreArchive(arcPath, dir, "ArcDat")
+	if err != nil {t.Fatalf("error -- RestoreArchive: %v", err)}
+
+	db, err = InitDb(dir, "ArcDat", false)
+	if err != nil {t.Fatalf("error -- InitDb after RestoreArchive: %v", err)}
+	defer db.Close()
+	valstr, err := db.GetVal(keys[3])
+	if err != nil || valstr != vals[3] {t.Errorf("error -- GetVal after RestoreArchive: %s %v", valstr, err)}
+}
+
+func TestPruneArchives(t *testing.T) {
+
+	dir := t.TempDir()
+	base := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	// two archives a day for 20 days
+	for i := 0; i < 40; i++ {
+		tim := base.Add(-time.Duration(i) * 12 * time.Hour)
+		err := os.WriteFile(dir + "/" + ArchiveName("tab", tim), nil, 0666)
+		if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	}
+	err := os.WriteFile(dir + "/" + ArchiveName("other", base), nil, 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	_, err = PruneArchives(dir, "tab", Retention{})
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- empty retention: %v is not ErrConfig", err)}
+
+	removed, err := PruneArchives(dir, "tab", Retention{KeepLast: 3, KeepDaily: 5, KeepWeekly: 4})
+	if err != nil {t.Fatalf("error -- PruneArchives: %v", err)}
+
+	list, err := ListArchives(dir, "tab")
+	if err != nil {t.Fatalf("error -- ListArchives: %v", err)}
+	// last 3 (days 0, 0, -1), daily adds days -2..-4, weekly adds 2 older weeks
+	if len(list) != 8 || len(removed) != 32 {t.Errorf("error -- kept %d removed %d expected 8 and 32", len(list), len(removed))}
+	if !list[0].Created.Equal(base) {t.Errorf("error -- newest archive removed")}
+
+	others, _ := ListArchives(dir, "other")
+	if len(others) != 1 {t.Errorf("error -- archive of another table removed")}
+}
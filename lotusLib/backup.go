@@ -0,0 +1,227 @@
+// backup
+// consistent backup and restore of a table
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// BackupManifestFilNam is the manifest in a backup directory.
+const BackupManifestFilNam = "backup.manifest"
+
+// BackupFile is a file of a backup.
+type BackupFile struct {
+	// Path is relative to the backup directory.
+	Path string `yaml:"Path"`
+	Size int64 `yaml:"Size"`
+	SHA256 string `yaml:"SHA256"`
+}
+
+// BackupManifest lists the files of a backup with their sizes and checksums.
+type BackupManifest struct {
+	DirPath string `yaml:"DirPath"`
+	TabNam string `yaml:"TableName"`
+	Created time.Time `yaml:"Created"`
+	Entries int `yaml:"Entries"`
+	Files []BackupFile `yaml:"Files"`
+}
+
+// Backup writes a point in time copy of the table to the new directory bckDir.
+// Writes are paused while the entries are copied into a new lotusdb table in bckDir;
+// reads continue. The backup holds the table files, the effective config, the
+// table manifest and BackupManifestFilNam with the checksums of the files.
+// The backup is written into bckDir.tmp, which is renamed to bckDir when it is complete.
+func (dbp *DBObj) Backup (bckDir string) (man *BackupManifest, err error){
+
+	_, err = os.Stat(bckDir)
+	if err == nil {return nil, fmt.Errorf("Backup: %s exists", bckDir)}
+
+	tmpDir := bckDir + ".tmp"
+	err = os.RemoveAll(tmpDir)
+	if err != nil {return nil, fmt.Errorf("Backup: %w", err)}
+
+	man, err = dbp.backup(tmpDir)
+	if err == nil {err = os.Rename(tmpDir, bckDir)}
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("Backup: %w", err)
+	}
+	return man, nil
+}
+
+// backup writes the backup into the new directory bckDir.
+func (dbp *DBObj) backup (bckDir string) (man *BackupManifest, err error){
+
+	bckOpt := dbp.Opt
+	bckOpt.DirPath = bckDir
+
+	man = &BackupManifest{DirPath: dbp.DirPath, TabNam: dbp.TabNam}
+
+	dbp.wrMu.Lock()
+	err = dbp.Db.Sync()
+	if err != nil {
+		dbp.wrMu.Unlock()
+		return nil, dbErr("Sync", err)
+	}
+	man.Created = time.Now().UTC()
+	man.Entries, err = copyEntries(dbp.Db, bckOpt)
+	dbp.wrMu.Unlock()
+	if err != nil {return nil, err}
+
+	err = dbp.saveOptionFile(bckDir + "/" + ConfigFilNam)
+	if err != nil {return nil, err}
+
+	err = copyFile(dbp.Opt.DirPath + "/" + ManifestFilNam, bckDir + "/" + ManifestFilNam)
+	if err != nil {return nil, err}
+
+	man.Files, err = hashFiles(bckDir)
+	if err != nil {return nil, err}
+
+	manData, err := yaml.Marshal(man)
+	if err != nil {return nil, fmt.Errorf("Marshal: %w", err)}
+
+	err = os.WriteFile(bckDir + "/" + BackupManifestFilNam, manData, 0666)
+	if err != nil {return nil, fmt.Errorf("WriteFile: %w", err)}
+
+	return man, nil
+}
+
+// hashFiles returns the files of dir, except the backup manifest, with their checksums.
+func hashFiles(dir string) (files []BackupFile, err error){
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {return err}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {return err}
+		if rel == BackupManifestFilNam {return nil}
+
+		size, sum, err := hashFile(path)
+		if err != nil {return err}
+		files = append(files, BackupFile{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+		return nil
+	})
+	return files, err
+}
+
+func hashFile(path string) (size int64, sum string, err error){
+
+	fil, err := os.Open(path)
+	if err != nil {return 0, "", err}
+	defer fil.Close()
+
+	h := sha256.New()
+	size, err = io.Copy(h, fil)
+	if err != nil {return 0, "", err}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadBackupManifest reads the manifest of the backup in bckDir.
+func ReadBackupManifest(bckDir string) (man *BackupManifest, err error){
+
+	manData, err := os.ReadFile(bckDir + "/" + BackupManifestFilNam)
+	if err != nil {return nil, fmt.Errorf("ReadFile: %w", err)}
+
+	man = &BackupManifest{}
+	err = yaml.Unmarshal(manData, man)
+	if err != nil {return nil, fmt.Errorf("backup manifest %s: %w", bckDir, err)}
+	return man, nil
+}
+
+// VerifyBackup checks the size and checksum of every file of the backup in dir.
+func (man *BackupManifest) VerifyBackup (dir string) (err error){
+
+	for _, bf := range man.Files {
+		size, sum, err := hashFile(dir + "/" + filepath.FromSlash(bf.Path))
+		if err != nil {return fmt.Errorf("%s: %w: %v", bf.Path, ErrChecksum, err)}
+		if size != bf.Size || sum != bf.SHA256 {return fmt.Errorf("%s: %w", bf.Path, ErrChecksum)}
+	}
+	return nil
+}
+
+// Restore replaces the table tabNam in dirPath with the backup in bckDir.
+// The checksums of the backup and of the copy are verified before the copy replaces the table.
+// The table must be closed; Restore holds the lock of the table until the swap and fails
+// with ErrDbLocked while the table is open.
+func Restore(bckDir, dirPath, tabNam string) (err error){
+
+	man, err := ReadBackupManifest(bckDir)
+	if err != nil {return fmt.Errorf("Restore: %w", err)}
+
+	err = man.VerifyBackup(bckDir)
+	if err != nil {return fmt.Errorf("Restore %s: %w", bckDir, err)}
+
+	tabDir := dirPath + "/" + tabNam
+	tmpDir := tabDir + ".restore"
+	oldDir := tabDir + ".old"
+
+	err = os.MkdirAll(dirPath, 0755)
+	if err != nil {return fmt.Errorf("Restore: %w", err)}
+	err = recoverSwap(tabDir)
+	if err != nil {return fmt.Errorf("Restore: %w", err)}
+
+	ldb, err := lockTable(tabDir)
+	if err != nil {return fmt.Errorf("Restore: %w", err)}
+
+	err = os.RemoveAll(tmpDir)
+	if err == nil {err = copyDir(bckDir, tmpDir)}
+	if err == nil {err = os.Remove(tmpDir + "/" + BackupManifestFilNam)}
+	if err == nil {err = man.VerifyBackup(tmpDir)}
+	if ldb != nil {
+		cerr := ldb.Close()
+		if err == nil && cerr != nil {err = dbErr("Close", cerr)}
+	}
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return fmt.Errorf("Restore: %w", err)
+	}
+
+	_, err = os.Stat(tabDir)
+	if err == nil {
+		err = os.Rename(tabDir, oldDir)
+		if err != nil {return fmt.Errorf("Restore: %w", err)}
+	}
+
+	err = os.Rename(tmpDir, tabDir)
+	if err != nil {
+		os.Rename(oldDir, tabDir)
+		return fmt.Errorf("Restore: %w", err)
+	}
+
+	err = os.RemoveAll(oldDir)
+	if err != nil {return fmt.Errorf("Restore: %w", err)}
+	return nil
+}
+
+// lockTable opens the lotusdb table in tabDir with its config, which takes the lock of
+// the table; it fails with ErrDbLocked while the table is open. A directory without a
+// config is not a table and is not locked.
+func lockTable(tabDir string) (ldb *lotusdb.DB, err error){
+
+	var optObj LotusDbOption
+	err = readOption(tabDir + "/" + ConfigFilNam, &optObj)
+	if errors.Is(err, fs.ErrNotExist) {return nil, nil}
+	if err != nil {return nil, err}
+
+	opt, _, _, _, err := optObj.Options()
+	if err != nil {return nil, err}
+	opt.DirPath = tabDir
+	ldb, err = lotusdb.Open(opt)
+	if err != nil {return nil, dbErr("lotusdb.Open " + tabDir, err)}
+	return ldb, nil
+}
@@ -0,0 +1,85 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "BckDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+
+	keys, vals, err := db.FillRan(20)
+	if err != nil {t.Fatalf("error -- FillRan: %v", err)}
+
+	bckDir := dir + "/backup"
+	man, err := db.Backup(bckDir)
+	if err != nil {t.Fatalf("error -- Backup: %v", err)}
+	if man.Entries != 20 || man.TabNam != "BckDat" || len(man.Files) == 0 {t.Errorf("error -- backup manifest: %+v", man)}
+
+	_, err = db.Backup(bckDir)
+	if err == nil {t.Errorf("error -- Backup into an existing directory")}
+	_, err = os.Stat(bckDir + ".tmp")
+	if !os.IsNotExist(err) {t.Errorf("error -- temporary backup directory: %v", err)}
+
+	// the table is locked while it is open
+	err = Restore(bckDir, dir, "BckDat")
+	if !errors.Is(err, ErrDbLocked) {t.Errorf("error -- Restore of an open table: %v is not ErrDbLocked", err)}
+
+	// a backup in the directory of the tables is not a table
+	cat, err := OpenCatalog(dir, false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+	if len(cat.List()) != 1 {t.Errorf("error -- catalog with a backup: %+v", cat.List())}
+
+	// changes after the backup are not restored
+	err = db.DelEntry(keys[0])
+	if err != nil {t.Errorf("error -- DelEntry: %v", err)}
+	err = db.AddEntry("later", "val")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	db.Close()
+
+	// the remains of an earlier restore are removed
+	err = os.Mkdir(dir + "/BckDat.old", 0755)
+	if err != nil {t.Fatalf("error -- Mkdir: %v", err)}
+
+	err = Restore(bckDir, dir, "BckDat")
+	if err != nil {t.Fatalf("error -- Restore: %v", err)}
+	_, err = os.Stat(dir + "/BckDat.old")
+	if !os.IsNotExist(err) {t.Errorf("error -- old table directory after Restore: %v", err)}
+
+	db, err = InitDb(dir, "BckDat", false)
+	if err != nil {t.Fatalf("error -- InitDb after Restore: %v", err)}
+	defer db.Close()
+	valstr, err := db.GetVal(keys[0])
+	if err != nil || valstr != vals[0] {t.Errorf("error -- GetVal after Restore: %s %v expected %s", valstr, err, vals[0])}
+	_, err = db.GetVal("later")
+	if !errors.Is(err, ErrKeyNotFound) {t.Errorf("error -- entry written after the backup: %v", err)}
+}
+
+func TestRestoreChecksum(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "BckDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	_, _, err = db.FillRan(5)
+	if err != nil {t.Fatalf("error -- FillRan: %v", err)}
+
+	bckDir := dir + "/backup"
+	man, err := db.Backup(bckDir)
+	if err != nil {t.Fatalf("error -- Backup: %v", err)}
+	db.Close()
+
+	err = os.WriteFile(bckDir + "/" + man.Files[0].Path, []byte("corrupt"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	err = Restore(bckDir, dir, "BckDat")
+	if !errors.Is(err, ErrChecksum) {t.Errorf("error -- Restore of a corrupt backup: %v is not ErrChecksum", err)}
+
+	// the table is unchanged
+	db, err = InitDb(dir, "BckDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	db.Close()
+}
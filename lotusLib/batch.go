@@ -0,0 +1,179 @@
+// batch
+// atomic multi-key writes on a lotusdb table
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// BatchObj collects writes that are committed atomically.
+// The writes are buffered until Commit, so an uncommitted batch does not hold
+// any lotusdb lock and Rollback simply discards them.
+// Get sees the writes of the batch (read your writes).
+// A batch is not safe for concurrent use.
+type BatchObj struct {
+	dbp *DBObj
+	opt lotusdb.BatchOptions
+	keys []string
+	vals map[string][]byte
+	dels map[string]bool
+	done bool
+}
+
+var _ KVBatch = (*BatchObj)(nil)
+
+// NewBatch starts a batch using the Batch options of the table.
+func (dbp *DBObj) NewBatch () (b *BatchObj){
+
+	dbp.optMu.RLock()
+	defer dbp.optMu.RUnlock()
+	b = &BatchObj{
+		dbp: dbp,
+		opt: dbp.BatchOpt,
+		vals: make(map[string][]byte),
+		dels: make(map[string]bool),
+	}
+	return b
+}
+
+func (b *BatchObj) check (op string, key []byte, write bool) (err error){
+
+	if b.done {return fmt.Errorf("%s: %w", op, ErrBatchDone)}
+	if len(key) == 0 {return fmt.Errorf("%s: %w", op, ErrKeyEmpty)}
+	if write && b.opt.ReadOnly {return fmt.Errorf("%s: %w", op, ErrReadOnly)}
+	return nil
+}
+
+// track records the first write of key, so that Commit applies the keys in order.
+func (b *BatchObj) track (key string) {
+
+	_, put := b.vals[key]
+	if !put && !b.dels[key] {b.keys = append(b.keys, key)}
+}
+
+func (b *BatchObj) Put (key, val []byte) (err error){
+
+	err = b.check("Batch Put", key, true)
+	if err != nil {return err}
+
+	b.track(string(key))
+	delete(b.dels, string(key))
+	b.vals[string(key)] = append([]byte(nil), val...)
+	return nil
+}
+
+func (b *BatchObj) Delete (key []byte) (err error){
+
+	err = b.check("Batch Delete", key, true)
+	if err != nil {return err}
+
+	b.track(string(key))
+	delete(b.vals, string(key))
+	b.dels[string(key)] = true
+	return nil
+}
+
+// Get returns the value of key as it will be after Commit.
+func (b *BatchObj) Get (key []byte) (val []byte, err error){
+
+	err = b.check("Batch Get", key, false)
+	if err != nil {return nil, err}
+
+	if v, ok := b.vals[string(key)]; ok {return append([]byte(nil), v...), nil}
+	if b.dels[string(key)] {return nil, fmt.Errorf("Batch Get: %w", ErrKeyNotFound)}
+
+	return b.dbp.Get(key)
+}
+
+func (b *BatchObj) Exists (key []byte) (res bool, err error){
+
+	err = b.check("Batch Exists", key, false)
+	if err != nil {return false, err}
+
+	if _, ok := b.vals[string(key)]; ok {return true, nil}
+	if b.dels[string(key)] {return false, nil}
+
+	return b.dbp.Exists(key)
+}
+
+// Keys returns the keys with prefix as they will be after Commit, in key order.
+func (b *BatchObj) Keys (prefix string) (keys []string, err error){
+
+	if b.done {return nil, fmt.Errorf("Batch Keys: %w", ErrBatchDone)}
+
+	it, err := b.dbp.NewIterator(WithPrefix(prefix), WithReverse(false))
+	if err != nil {return nil, err}
+	for it.Next() {
+		key := it.Key()
+		_, put := b.vals[key]
+		if !put && !b.dels[key] {keys = append(keys, key)}
+	}
+	err = it.Close()
+	if err != nil {return nil, err}
+
+	for key := range b.vals {
+		if strings.HasPrefix(key, prefix) {keys = append(keys, key)}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Len returns the number of keys written by the batch.
+func (b *BatchObj) Len () int {
+	return len(b.keys)
+}
+
+// Commit writes all entries of the batch atomically with a lotusdb batch.
+// The Write options of the table apply, the Batch Sync option or WithSync force an fsync.
+// The batch cannot be used after Commit, even if Commit fails.
+func (b *BatchObj) Commit (opts ...WriteOption) (err error){
+
+	if b.done {return fmt.Errorf("Commit: %w", ErrBatchDone)}
+	b.done = true
+	if b.opt.ReadOnly || len(b.keys) == 0 {return nil}
+
+	wopt := b.dbp.writeOptions(opts)
+	if b.opt.Sync {wopt.Sync = true}
+
+	b.dbp.wrMu.RLock()
+	defer b.dbp.wrMu.RUnlock()
+	db := b.dbp.Db
+	batch := db.NewBatch(b.opt)
+	for _, key := range b.keys {
+		if b.dels[key] {
+			err = batch.Delete([]byte(key))
+		} else {
+			err = batch.Put([]byte(key), b.vals[key])
+		}
+		if err != nil {
+			// keys are checked by Put and Delete, so only a closed db fails here;
+			// Rollback discards the staged writes and releases the lotusdb batch lock
+			batch.Rollback()
+			return dbErr("Commit " + key, err)
+		}
+	}
+
+	err = batch.Commit(wopt)
+	if err != nil {return dbErr("Commit", err)}
+	return nil
+}
+
+// Rollback discards all writes of the batch.
+func (b *BatchObj) Rollback () (err error){
+
+	if b.done {return fmt.Errorf("Rollback: %w", ErrBatchDone)}
+	b.done = true
+	b.keys = nil
+	b.vals = nil
+	b.dels = nil
+	return nil
+}
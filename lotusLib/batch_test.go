@@ -0,0 +1,104 @@
+package lotusLib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBatchCommit(t *testing.T) {
+
+	db, err := InitDb(t.TempDir(), "BatchDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	err = db.AddEntry("inventory:1", "10")
+	if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+
+	b := db.NewBatch()
+	err = b.Put([]byte("order:1"), []byte("item 1"))
+	if err != nil {t.Errorf("error -- Batch Put: %v", err)}
+	err = b.Put([]byte("inventory:1"), []byte("9"))
+	if err != nil {t.Errorf("error -- Batch Put: %v", err)}
+	err = b.Delete([]byte("order:1"))
+	if err != nil {t.Errorf("error -- Batch Delete: %v", err)}
+	err = b.Put([]byte("order:1"), []byte("item 1 x1"))
+	if err != nil {t.Errorf("error -- Batch Put: %v", err)}
+
+	// read your writes
+	val, err := b.Get([]byte("inventory:1"))
+	if err != nil || string(val) != "9" {t.Errorf("error -- Batch Get: %s %v", val, err)}
+	valstr, err := db.GetVal("inventory:1")
+	if err != nil || valstr != "10" {t.Errorf("error -- GetVal before Commit: %s %v", valstr, err)}
+	res, err := db.FindKey("order:1")
+	if err != nil || res {t.Errorf("error -- FindKey before Commit: %t %v", res, err)}
+	if b.Len() != 2 {t.Errorf("error -- Batch Len: %d expected 2", b.Len())}
+
+	err = b.Commit()
+	if err != nil {t.Fatalf("error -- Commit: %v", err)}
+
+	valstr, err = db.GetVal("order:1")
+	if err != nil || valstr != "item 1 x1" {t.Errorf("error -- GetVal after Commit: %s %v", valstr, err)}
+	valstr, err = db.GetVal("inventory:1")
+	if err != nil || valstr != "9" {t.Errorf("error -- GetVal after Commit: %s %v", valstr, err)}
+
+	err = b.Put([]byte("order:2"), []byte("item 2"))
+	if !errors.Is(err, ErrBatchDone) {t.Errorf("error -- Put after Commit: %v is not ErrBatchDone", err)}
+	err = b.Commit()
+	if !errors.Is(err, ErrBatchDone) {t.Errorf("error -- second Commit: %v is not ErrBatchDone", err)}
+}
+
+func TestBatchRollback(t *testing.T) {
+
+	db, err := InitDb(t.TempDir(), "BatchDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	err = db.AddEntry("key1", "val1")
+	if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+
+	b := db.NewBatch()
+	err = b.Delete([]byte("key1"))
+	if err != nil {t.Errorf("error -- Batch Delete: %v", err)}
+	_, err = b.Get([]byte("key1"))
+	if !errors.Is(err, ErrKeyNotFound) {t.Errorf("error -- Batch Get of deleted key: %v is not ErrKeyNotFound", err)}
+
+	err = b.Rollback()
+	if err != nil {t.Fatalf("error -- Rollback: %v", err)}
+
+	valstr, err := db.GetVal("key1")
+	if err != nil || valstr != "val1" {t.Errorf("error -- GetVal after Rollback: %s %v", valstr, err)}
+
+	db.BatchOpt.ReadOnly = true
+	b = db.NewBatch()
+	err = b.Put([]byte("key2"), []byte("val2"))
+	if !errors.Is(err, ErrReadOnly) {t.Errorf("error -- Put in read only batch: %v is not ErrReadOnly", err)}
+	val, err := b.Get([]byte("key1"))
+	if err != nil || string(val) != "val1" {t.Errorf("error -- Get in read only batch: %s %v", val, err)}
+	err = b.Commit()
+	if err != nil {t.Errorf("error -- Commit of read only batch: %v", err)}
+}
+
+func TestBatchKeys(t *testing.T) {
+
+	db, err := InitDb(t.TempDir(), "BatchDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	for _, key := range []string{"a1", "b1", "b3"} {
+		err = db.AddEntry(key, "val")
+		if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+	}
+
+	b := db.NewBatch()
+	b.Put([]byte("b2"), []byte("new"))
+	b.Put([]byte("b3"), []byte("new"))
+	b.Delete([]byte("b1"))
+
+	keys, err := b.Keys("b")
+	if err != nil || fmt.Sprint(keys) != "[b2 b3]" {t.Errorf("error -- Batch Keys: %v %v", keys, err)}
+
+	b.Rollback()
+	_, err = b.Keys("b")
+	if !errors.Is(err, ErrBatchDone) {t.Errorf("error -- Keys after Rollback: %v is not ErrBatchDone", err)}
+}
@@ -0,0 +1,511 @@
+// catalog
+// the tables of a directory
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// CatalogFilNam is the catalog file in DirPath.
+const CatalogFilNam = "catalog.yaml"
+
+// SidecarSep separates the table name and the kind of a sidecar table, e.g. users@ttl.
+// Table names cannot contain it.
+const SidecarSep = "@"
+
+// TableInfo is the catalog entry of a table.
+type TableInfo struct {
+	Name string `yaml:"Name"`
+	Desc string `yaml:"Description,omitempty"`
+	Created time.Time `yaml:"Created"`
+	// Options are the options of the table; they are refreshed when the catalog opens
+	// the table and by Describe while the table is open.
+	Options LotusDbOption `yaml:"Options"`
+}
+
+type catalogFile struct {
+	Tables []TableInfo `yaml:"Tables"`
+}
+
+// Catalog manages the tables DirPath/TabNam of a directory.
+// The table metadata is kept in CatalogFilNam; open tables are cached until Close.
+type Catalog struct {
+	DirPath string
+	Dbg bool
+
+	mu sync.Mutex
+	tables map[string]*TableInfo
+	open map[string]*DBObj
+}
+
+// OpenCatalog opens the catalog of dirPath and creates the directory if needed.
+// Tables in dirPath with a manifest but without a catalog entry are added to the catalog.
+func OpenCatalog(dirPath string, dbg bool) (cat *Catalog, err error){
+
+	err = os.MkdirAll(dirPath, 0755)
+	if err != nil {return nil, fmt.Errorf("OpenCatalog: %w", err)}
+
+	cat, added, err := readCatalog(dirPath, dbg)
+	if err != nil {return nil, err}
+	if added {
+		err = cat.save()
+		if err != nil {return nil, err}
+	}
+	return cat, nil
+}
+
+// ListTables returns the tables of dirPath, as OpenCatalog lists them, sorted by name.
+// It does not create the directory or write the catalog file.
+func ListTables(dirPath string) (list []TableInfo, err error){
+
+	cat, _, err := readCatalog(dirPath, false)
+	if err != nil {return nil, err}
+	return cat.list(), nil
+}
+
+// readCatalog reads the catalog file of dirPath and adds the tables without an entry;
+// added is set if the catalog file is not up to date.
+func readCatalog(dirPath string, dbg bool) (cat *Catalog, added bool, err error){
+
+	cat = &Catalog{
+		DirPath: dirPath,
+		Dbg: dbg,
+		tables: make(map[string]*TableInfo),
+		open: make(map[string]*DBObj),
+	}
+
+	catData, err := os.ReadFile(cat.filPath())
+	if err != nil && !os.IsNotExist(err) {return nil, false, fmt.Errorf("OpenCatalog: %w", err)}
+	if err == nil {
+		var catFil catalogFile
+		err = yaml.Unmarshal(catData, &catFil)
+		if err != nil {return nil, false, fmt.Errorf("OpenCatalog %s: %w", cat.filPath(), err)}
+		for i := range catFil.Tables {
+			info := catFil.Tables[i]
+			cat.tables[info.Name] = &info
+		}
+	}
+
+	added, err = cat.adopt()
+	if err != nil {return nil, false, err}
+	return cat, added, nil
+}
+
+// IsTable reports whether the table dirPath/tabNam exists, i.e. its directory has a
+// manifest or a config.
+func IsTable(dirPath, tabNam string) (ok bool, err error){
+
+	tabDir := dirPath + "/" + tabNam
+	for _, filNam := range []string{ManifestFilNam, ConfigFilNam} {
+		_, err = os.Stat(tabDir + "/" + filNam)
+		if err == nil {return true, nil}
+		if !os.IsNotExist(err) {return false, err}
+	}
+	return false, nil
+}
+
+func (cat *Catalog) filPath() string {
+	return cat.DirPath + "/" + CatalogFilNam
+}
+
+// adopt adds the tables of DirPath that are not in the catalog. Backups are not tables.
+func (cat *Catalog) adopt() (added bool, err error){
+
+	entries, err := os.ReadDir(cat.DirPath)
+	if err != nil {return false, fmt.Errorf("OpenCatalog: %w", err)}
+
+	for _, ent := range entries {
+		name := ent.Name()
+		if !ent.IsDir() || cat.tables[name] != nil || strings.Contains(name, SidecarSep) {continue}
+		_, err = os.Stat(cat.DirPath + "/" + name + "/" + BackupManifestFilNam)
+		if err == nil {continue}
+		man, ok, err := ReadManifest(cat.DirPath + "/" + name)
+		if err != nil || !ok {continue}
+
+		info := TableInfo{Name: name, Created: man.Created}
+		cfgPath := cat.DirPath + "/" + name + "/" + ConfigFilNam
+		err = readOption(cfgPath, &info.Options)
+		if err != nil {continue}
+		info.Options.DirPath = cat.DirPath
+		info.Options.TabNam = name
+		cat.tables[name] = &info
+		added = true
+	}
+	return added, nil
+}
+
+// save writes the catalog file; the caller holds mu.
+func (cat *Catalog) save() (err error){
+
+	var catFil catalogFile
+	for _, info := range cat.list() {
+		catFil.Tables = append(catFil.Tables, info)
+	}
+
+	catData, err := yaml.Marshal(catFil)
+	if err != nil {return fmt.Errorf("Marshal: %w", err)}
+
+	tmpPath := cat.filPath() + ".tmp"
+	err = os.WriteFile(tmpPath, catData, 0666)
+	if err != nil {return fmt.Errorf("WriteFile: %w", err)}
+	err = os.Rename(tmpPath, cat.filPath())
+	if err != nil {return fmt.Errorf("save catalog: %w", err)}
+	return nil
+}
+
+func checkTabNam(name string) (err error){
+
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, "/\\" + SidecarSep) || name == CatalogFilNam {
+		return &ConfigError{Field: "TableName", Reason: fmt.Sprintf("invalid table name %q", name)}
+	}
+	return nil
+}
+
+// Create creates the table name with the description desc and returns the open table.
+// The options opts, e.g. WithPreset, set the options of the table.
+func (cat *Catalog) Create(name, desc string, opts ...InitOption) (dbpt *DBObj, err error){
+
+	err = checkTabNam(name)
+	if err != nil {return nil, err}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	if cat.tables[name] != nil {return nil, fmt.Errorf("Create %s: %w", name, ErrTableExists)}
+	_, err = os.Stat(cat.DirPath + "/" + name)
+	if err == nil {return nil, fmt.Errorf("Create %s: directory exists: %w", name, ErrTableExists)}
+
+	// sidecar tables left by an interrupted Drop do not belong to the new table
+	sideList, err := cat.sidecars(name)
+	if err != nil {return nil, fmt.Errorf("Create %s: %w", name, err)}
+	for _, sideNam := range sideList {
+		err = os.RemoveAll(cat.DirPath + "/" + sideNam)
+		if err != nil {return nil, fmt.Errorf("Create %s: %w", name, err)}
+	}
+
+	db, err := InitDb(cat.DirPath, name, cat.Dbg, opts...)
+	if err != nil {return nil, err}
+
+	info := TableInfo{Name: name, Desc: desc, Created: time.Now().UTC()}
+	info.Options, err = db.LotusDbOption()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	cat.tables[name] = &info
+	err = cat.save()
+	if err != nil {
+		db.Close()
+		delete(cat.tables, name)
+		return nil, err
+	}
+
+	cat.open[name] = db
+	return db, nil
+}
+
+// Open returns the open table name. The table is opened once and the handle is
+// shared by all callers until CloseTable or Close. Rename, Copy and Drop fail with
+// ErrTableOpen while the table is open, so they never close a handle in use.
+func (cat *Catalog) Open(name string) (dbpt *DBObj, err error){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	if cat.tables[name] == nil {return nil, fmt.Errorf("Open %s: %w", name, ErrTableNotFound)}
+	db, ok := cat.open[name]
+	if ok {return db, nil}
+
+	db, err = InitDb(cat.DirPath, name, cat.Dbg)
+	if err != nil {return nil, err}
+	cat.open[name] = db
+
+	err = cat.refresh(name, db)
+	if err != nil {return nil, err}
+	return db, nil
+}
+
+// OpenSidecar returns the open sidecar table kind of the table name, which holds data
+// a server keeps about the table, e.g. the expiry times of the redis server.
+// A sidecar table is created when it is first opened and is not listed. It is closed,
+// renamed, copied and dropped with its table.
+func (cat *Catalog) OpenSidecar(name, kind string) (dbpt *DBObj, err error){
+
+	err = checkTabNam(kind)
+	if err != nil {return nil, err}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	if cat.tables[name] == nil {return nil, fmt.Errorf("OpenSidecar %s: %w", name, ErrTableNotFound)}
+	sideNam := name + SidecarSep + kind
+	db, ok := cat.open[sideNam]
+	if ok {return db, nil}
+
+	db, err = InitDb(cat.DirPath, sideNam, cat.Dbg)
+	if err != nil {return nil, err}
+	cat.open[sideNam] = db
+	return db, nil
+}
+
+// sidecars returns the directory names of the sidecar tables of the table name.
+func (cat *Catalog) sidecars(name string) (list []string, err error){
+
+	entries, err := os.ReadDir(cat.DirPath)
+	if err != nil {return nil, err}
+	for _, ent := range entries {
+		if ent.IsDir() && strings.HasPrefix(ent.Name(), name + SidecarSep) {list = append(list, ent.Name())}
+	}
+	return list, nil
+}
+
+// refresh updates the options of the catalog entry name from the open table db; the caller holds mu.
+func (cat *Catalog) refresh(name string, db *DBObj) (err error){
+
+	optObj, err := db.LotusDbOption()
+	if err != nil {return err}
+	info := cat.tables[name]
+	if reflect.DeepEqual(info.Options, optObj) {return nil}
+	info.Options = optObj
+	return cat.save()
+}
+
+// List returns the catalog entries sorted by name.
+func (cat *Catalog) List() (list []TableInfo){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	return cat.list()
+}
+
+func (cat *Catalog) list() (list []TableInfo){
+
+	for _, info := range cat.tables {
+		list = append(list, *info)
+	}
+	sort.Slice(list, func(i, j int) bool {return list[i].Name < list[j].Name})
+	return list
+}
+
+// Describe returns the catalog entry of the table name.
+func (cat *Catalog) Describe(name string) (info TableInfo, err error){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	infop := cat.tables[name]
+	if infop == nil {return info, fmt.Errorf("Describe %s: %w", name, ErrTableNotFound)}
+	db, ok := cat.open[name]
+	if ok {
+		err = cat.refresh(name, db)
+		if err != nil {return info, err}
+	}
+	return *infop, nil
+}
+
+// SetDesc changes the description of the table name.
+func (cat *Catalog) SetDesc(name, desc string) (err error){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	info := cat.tables[name]
+	if info == nil {return fmt.Errorf("SetDesc %s: %w", name, ErrTableNotFound)}
+	old := info.Desc
+	info.Desc = desc
+	err = cat.save()
+	if err != nil {info.Desc = old}
+	return err
+}
+
+// CloseTable closes the open table name. The handles returned by Open must not be used afterwards.
+func (cat *Catalog) CloseTable(name string) (err error){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	if cat.tables[name] == nil {return fmt.Errorf("CloseTable %s: %w", name, ErrTableNotFound)}
+	return cat.closeTable(name)
+}
+
+// checkClosed returns ErrTableOpen if the table name is open; the caller holds mu.
+func (cat *Catalog) checkClosed(op, name string) (err error){
+
+	for openNam := range cat.open {
+		if openNam == name || strings.HasPrefix(openNam, name + SidecarSep) {return fmt.Errorf("%s %s: %w", op, name, ErrTableOpen)}
+	}
+	return nil
+}
+
+// closeTable closes the cached table name and its sidecar tables; the caller holds mu.
+func (cat *Catalog) closeTable(name string) (err error){
+
+	for openNam, db := range cat.open {
+		if openNam != name && !strings.HasPrefix(openNam, name + SidecarSep) {continue}
+		delete(cat.open, openNam)
+		cerr := db.Close()
+		if cerr != nil && err == nil {err = cerr}
+	}
+	return err
+}
+
+// Rename renames the table oldNam to newNam. The table must not be open.
+func (cat *Catalog) Rename(oldNam, newNam string) (err error){
+
+	err = checkTabNam(newNam)
+	if err != nil {return err}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	info := cat.tables[oldNam]
+	if info == nil {return fmt.Errorf("Rename %s: %w", oldNam, ErrTableNotFound)}
+	if cat.tables[newNam] != nil {return fmt.Errorf("Rename %s: %s: %w", oldNam, newNam, ErrTableExists)}
+	_, err = os.Stat(cat.DirPath + "/" + newNam)
+	if err == nil {return fmt.Errorf("Rename %s: %s: directory exists: %w", oldNam, newNam, ErrTableExists)}
+	dstList, err := cat.sidecars(newNam)
+	if err != nil {return fmt.Errorf("Rename %s: %w", oldNam, err)}
+	if len(dstList) > 0 {return fmt.Errorf("Rename %s: %s: directory exists: %w", oldNam, dstList[0], ErrTableExists)}
+
+	err = cat.checkClosed("Rename", oldNam)
+	if err != nil {return err}
+	sideList, err := cat.sidecars(oldNam)
+	if err != nil {return fmt.Errorf("Rename %s: %w", oldNam, err)}
+
+	err = os.Rename(cat.DirPath + "/" + oldNam, cat.DirPath + "/" + newNam)
+	if err != nil {return fmt.Errorf("Rename %s: %w", oldNam, err)}
+	for _, sideNam := range sideList {
+		err = os.Rename(cat.DirPath + "/" + sideNam, cat.DirPath + "/" + newNam + strings.TrimPrefix(sideNam, oldNam))
+		if err != nil {return fmt.Errorf("Rename %s: %w", oldNam, err)}
+	}
+
+	delete(cat.tables, oldNam)
+	info.Name = newNam
+	info.Options.TabNam = newNam
+	cat.tables[newNam] = info
+	return cat.save()
+}
+
+// Copy copies the table srcNam to the new table dstNam. The source table must not be open.
+func (cat *Catalog) Copy(srcNam, dstNam, desc string) (err error){
+
+	err = checkTabNam(dstNam)
+	if err != nil {return err}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	info := cat.tables[srcNam]
+	if info == nil {return fmt.Errorf("Copy %s: %w", srcNam, ErrTableNotFound)}
+	if cat.tables[dstNam] != nil {return fmt.Errorf("Copy %s: %s: %w", srcNam, dstNam, ErrTableExists)}
+	_, err = os.Stat(cat.DirPath + "/" + dstNam)
+	if err == nil {return fmt.Errorf("Copy %s: %s: directory exists: %w", srcNam, dstNam, ErrTableExists)}
+	dstList, err := cat.sidecars(dstNam)
+	if err != nil {return fmt.Errorf("Copy %s: %w", srcNam, err)}
+	if len(dstList) > 0 {return fmt.Errorf("Copy %s: %s: directory exists: %w", srcNam, dstList[0], ErrTableExists)}
+
+	err = cat.checkClosed("Copy", srcNam)
+	if err != nil {return err}
+	sideList, err := cat.sidecars(srcNam)
+	if err != nil {return fmt.Errorf("Copy %s: %w", srcNam, err)}
+
+	dstDir := cat.DirPath + "/" + dstNam
+	err = copyDir(cat.DirPath + "/" + srcNam, dstDir)
+	for _, sideNam := range sideList {
+		if err != nil {break}
+		err = copyDir(cat.DirPath + "/" + sideNam, cat.DirPath + "/" + dstNam + strings.TrimPrefix(sideNam, srcNam))
+	}
+	if err != nil {
+		os.RemoveAll(dstDir)
+		for _, sideNam := range sideList {
+			os.RemoveAll(cat.DirPath + "/" + dstNam + strings.TrimPrefix(sideNam, srcNam))
+		}
+		return fmt.Errorf("Copy %s: %w", srcNam, err)
+	}
+
+	dstInfo := TableInfo{Name: dstNam, Desc: desc, Created: time.Now().UTC(), Options: info.Options}
+	dstInfo.Options.TabNam = dstNam
+	cat.tables[dstNam] = &dstInfo
+	return cat.save()
+}
+
+// Drop removes the directory and catalog entry of the table name. The table must not be open.
+func (cat *Catalog) Drop(name string) (err error){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	if cat.tables[name] == nil {return fmt.Errorf("Drop %s: %w", name, ErrTableNotFound)}
+
+	err = cat.checkClosed("Drop", name)
+	if err != nil {return err}
+	sideList, err := cat.sidecars(name)
+	if err != nil {return fmt.Errorf("Drop %s: %w", name, err)}
+
+	for _, sideNam := range append(sideList, name) {
+		err = os.RemoveAll(cat.DirPath + "/" + sideNam)
+		if err != nil {return fmt.Errorf("Drop %s: %w", name, err)}
+	}
+
+	delete(cat.tables, name)
+	return cat.save()
+}
+
+// Close closes all open tables.
+func (cat *Catalog) Close() (err error){
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	for name := range cat.open {
+		cerr := cat.closeTable(name)
+		if cerr != nil && err == nil {err = cerr}
+	}
+	return err
+}
+
+// copyDir copies the files of the directory src into the new directory dst.
+func copyDir(src, dst string) (err error){
+
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {return err}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {return err}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {return os.Mkdir(target, 0755)}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) (err error){
+
+	in, err := os.Open(src)
+	if err != nil {return err}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {return err}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
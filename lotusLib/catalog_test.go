@@ -0,0 +1,153 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+
+	dir := t.TempDir()
+	cat, err := OpenCatalog(dir, false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+
+	db, err := cat.Create("users", "user accounts", WithPreset("low-memory"))
+	if err != nil {t.Fatalf("error -- Create: %v", err)}
+	err = db.AddEntry("user:1", "alice")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+
+	_, err = cat.Create("users", "", WithPreset("low-memory"))
+	if !errors.Is(err, ErrTableExists) {t.Errorf("error -- Create of an existing table: %v is not ErrTableExists", err)}
+	_, err = cat.Create("../users", "")
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- Create with an invalid name: %v is not ErrConfig", err)}
+
+	db2, err := cat.Open("users")
+	if err != nil || db2 != db {t.Errorf("error -- Open does not return the cached table: %v", err)}
+	_, err = cat.Open("orders")
+	if !errors.Is(err, ErrTableNotFound) {t.Errorf("error -- Open of a missing table: %v is not ErrTableNotFound", err)}
+
+	info, err := cat.Describe("users")
+	if err != nil || info.Desc != "user accounts" || info.Options.PartitionNum != 1 {t.Errorf("error -- Describe: %+v %v", info, err)}
+
+	// an open table is not copied, renamed or dropped
+	err = cat.Copy("users", "users2", "copy of users")
+	if !errors.Is(err, ErrTableOpen) {t.Errorf("error -- Copy of an open table: %v is not ErrTableOpen", err)}
+	err = cat.Drop("users")
+	if !errors.Is(err, ErrTableOpen) {t.Errorf("error -- Drop of an open table: %v is not ErrTableOpen", err)}
+	valstr, err := db.GetVal("user:1")
+	if err != nil || valstr != "alice" {t.Errorf("error -- GetVal after a refused Drop: %s %v", valstr, err)}
+
+	err = cat.CloseTable("users")
+	if err != nil {t.Fatalf("error -- CloseTable: %v", err)}
+	err = cat.Copy("users", "users2", "copy of users")
+	if err != nil {t.Fatalf("error -- Copy: %v", err)}
+	// a directory that is not a table is not replaced
+	err = os.Mkdir(dir + "/other", 0755)
+	if err != nil {t.Fatalf("error -- Mkdir: %v", err)}
+	err = cat.Rename("users2", "other")
+	if !errors.Is(err, ErrTableExists) {t.Errorf("error -- Rename onto a directory: %v is not ErrTableExists", err)}
+	err = cat.Copy("users", "other", "")
+	if !errors.Is(err, ErrTableExists) {t.Errorf("error -- Copy onto a directory: %v is not ErrTableExists", err)}
+	_, err = os.Stat(dir + "/other")
+	if err != nil {t.Errorf("error -- directory removed by Copy: %v", err)}
+
+	err = cat.Rename("users2", "backup")
+	if err != nil {t.Fatalf("error -- Rename: %v", err)}
+
+	db, err = cat.Open("backup")
+	if err != nil {t.Fatalf("error -- Open of the copy: %v", err)}
+	valstr, err = db.GetVal("user:1")
+	if err != nil || valstr != "alice" {t.Errorf("error -- GetVal of the copy: %s %v", valstr, err)}
+
+	// the options of the catalog entry follow the table
+	db, err = InitDb(dir, "users", false, WithPreset("durable"))
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	db.Close()
+	_, err = cat.Open("users")
+	if err != nil {t.Fatalf("error -- Open: %v", err)}
+	info, err = cat.Describe("users")
+	if err != nil || !info.Options.Sync || !info.Options.Batch.Sync {t.Errorf("error -- Describe after the options changed: %+v %v", info.Options, err)}
+	err = cat.CloseTable("users")
+	if err != nil {t.Errorf("error -- CloseTable: %v", err)}
+
+	err = cat.Drop("users")
+	if err != nil {t.Errorf("error -- Drop: %v", err)}
+
+	list := cat.List()
+	if len(list) != 1 || list[0].Name != "backup" || list[0].Desc != "copy of users" {t.Errorf("error -- List: %+v", list)}
+
+	err = cat.Close()
+	if err != nil {t.Errorf("error -- Close: %v", err)}
+
+	// the catalog is persisted and adopts tables created with InitDb
+	db, err = InitDb(dir, "logs", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	db.Close()
+
+	// ListTables lists the new table without writing the catalog
+	catData, err := os.ReadFile(dir + "/" + CatalogFilNam)
+	if err != nil {t.Fatalf("error -- ReadFile: %v", err)}
+	list, err = ListTables(dir)
+	if err != nil || len(list) != 2 || list[1].Name != "logs" {t.Errorf("error -- ListTables: %+v %v", list, err)}
+	catData2, err := os.ReadFile(dir + "/" + CatalogFilNam)
+	if err != nil || string(catData2) != string(catData) {t.Errorf("error -- ListTables changed the catalog: %v", err)}
+	ok, err := IsTable(dir, "logs")
+	if err != nil || !ok {t.Errorf("error -- IsTable: %t %v", ok, err)}
+	ok, err = IsTable(dir, "other")
+	if err != nil || ok {t.Errorf("error -- IsTable of a directory that is not a table: %t %v", ok, err)}
+
+	cat, err = OpenCatalog(dir, false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+	defer cat.Close()
+	list = cat.List()
+	if len(list) != 2 || list[0].Name != "backup" || list[1].Name != "logs" {t.Errorf("error -- List after reopen: %+v", list)}
+	_, err = cat.Open("logs")
+	if err != nil {t.Errorf("error -- Open of an adopted table: %v", err)}
+}
+
+func TestSidecar(t *testing.T) {
+
+	dir := t.TempDir()
+	cat, err := OpenCatalog(dir, false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+	defer cat.Close()
+
+	_, err = cat.Create("a" + SidecarSep + "ttl", "")
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- Create of a sidecar name: %v is not ErrConfig", err)}
+	_, err = cat.OpenSidecar("users", "ttl")
+	if !errors.Is(err, ErrTableNotFound) {t.Errorf("error -- OpenSidecar of a missing table: %v is not ErrTableNotFound", err)}
+
+	_, err = cat.Create("users", "")
+	if err != nil {t.Fatalf("error -- Create: %v", err)}
+	side, err := cat.OpenSidecar("users", "ttl")
+	if err != nil {t.Fatalf("error -- OpenSidecar: %v", err)}
+	err = side.AddEntry("k", "v")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+
+	// the sidecar is closed with its table and is not listed
+	err = cat.CloseTable("users")
+	if err != nil {t.Errorf("error -- CloseTable: %v", err)}
+	list, err := ListTables(dir)
+	if err != nil || len(list) != 1 {t.Errorf("error -- ListTables with a sidecar: %+v %v", list, err)}
+
+	// the sidecar moves with Rename and Copy and is removed by Drop
+	err = cat.Rename("users", "people")
+	if err != nil {t.Fatalf("error -- Rename: %v", err)}
+	err = cat.Copy("people", "people2", "")
+	if err != nil {t.Fatalf("error -- Copy: %v", err)}
+	for _, name := range []string{"people", "people2"} {
+		side, err = cat.OpenSidecar(name, "ttl")
+		if err != nil {t.Fatalf("error -- OpenSidecar %s: %v", name, err)}
+		valstr, err := side.GetVal("k")
+		if err != nil || valstr != "v" {t.Errorf("error -- sidecar of %s: %s %v", name, valstr, err)}
+		err = cat.Drop(name)
+		if !errors.Is(err, ErrTableOpen) {t.Errorf("error -- Drop with an open sidecar: %v is not ErrTableOpen", err)}
+		err = cat.CloseTable(name)
+		if err != nil {t.Errorf("error -- CloseTable: %v", err)}
+	}
+	err = cat.Drop("people")
+	if err != nil {t.Errorf("error -- Drop: %v", err)}
+	_, err = os.Stat(dir + "/people" + SidecarSep + "ttl")
+	if !os.IsNotExist(err) {t.Errorf("error -- sidecar after Drop: %v", err)}
+}
@@ -0,0 +1,69 @@
+// config
+// open a table from a yaml config file
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// ConfigFilNam is the effective config that is kept in the table directory DirPath/TabNam.
+// InitDb reopens a table with it.
+const ConfigFilNam = "lotusLib.yaml"
+
+// OpenFromConfig opens the table described by the yaml config file cfgPath.
+// The config is loaded and validated before lotusdb is opened; keys missing in the
+// file use the lotusdb defaults. TableName is required; an empty DirPath is the
+// directory of the config file.
+// The options opts, e.g. WithMigration, are applied after the config.
+func OpenFromConfig(cfgPath string, dbg bool, opts ...InitOption) (dbpt *DBObj, err error){
+
+	db := newDb("", "", dbg)
+
+	optObj, err := db.LotusDbOption()
+	if err != nil {return nil, err}
+
+	// keys with invalid values are reported with the validation errors
+	err = readOption(cfgPath, &optObj)
+	var keyErrs ConfigErrors
+	if err != nil && !errors.As(err, &keyErrs) {return nil, err}
+
+	if len(optObj.DirPath) == 0 {optObj.DirPath = filepath.Dir(cfgPath)}
+
+	return openOption(&optObj, dbg, keyErrs, opts)
+}
+
+// OpenOption opens the table DirPath/TableName of optObj with its options.
+func OpenOption(optObj *LotusDbOption, dbg bool, opts ...InitOption) (dbpt *DBObj, err error){
+	return openOption(optObj, dbg, nil, opts)
+}
+
+func openOption(optObj *LotusDbOption, dbg bool, keyErrs ConfigErrors, opts []InitOption) (dbpt *DBObj, err error){
+
+	if len(optObj.DirPath) == 0 || len(optObj.TabNam) == 0 {
+		errs := append(ConfigErrors(nil), keyErrs...)
+		if len(optObj.DirPath) == 0 {errs.add("DirPath", "the database directory path cannot be empty")}
+		if len(optObj.TabNam) == 0 {errs.add("TableName", "the table name cannot be empty")}
+		return nil, errs
+	}
+
+	db := newDb(optObj.DirPath, optObj.TabNam, dbg)
+
+	err = db.setOption(optObj, keyErrs...)
+	if err != nil {return nil, err}
+
+	for _, opt := range opts {
+		err = opt(db)
+		if err != nil {return nil, err}
+	}
+
+	err = db.open()
+	if err != nil {return nil, err}
+
+	return db, nil
+}
@@ -0,0 +1,61 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestOpenFromConfig(t *testing.T) {
+
+	dir := t.TempDir()
+	cfg := "TableName: CfgDat\nPartitions: 2\nMemoryTableSize: 32MiB\nIndexType: hash\n"
+	cfgPath := dir + "/cfg.yaml"
+	err := os.WriteFile(cfgPath, []byte(cfg), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	db, err := OpenFromConfig(cfgPath, false)
+	if err != nil {t.Fatalf("error -- OpenFromConfig: %v", err)}
+	if db.DirPath != dir || db.TabNam != "CfgDat" {t.Errorf("error -- DirPath %s TabNam %s", db.DirPath, db.TabNam)}
+	if db.Opt.PartitionNum != 2 || db.Opt.MemtableSize != 32 << 20 {t.Errorf("error -- options not loaded: %d %d", db.Opt.PartitionNum, db.Opt.MemtableSize)}
+
+	err = db.AddEntry("key1", "val1")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	err = db.Close()
+	if err != nil {t.Fatalf("error -- Close: %v", err)}
+
+	_, err = os.Stat(dir + "/CfgDat/" + ConfigFilNam)
+	if err != nil {t.Fatalf("error -- effective config not saved: %v", err)}
+
+	// reopen uses the persisted config
+	db, err = InitDb(dir, "CfgDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+	if db.Opt.PartitionNum != 2 || db.Opt.MemtableSize != 32 << 20 {t.Errorf("error -- persisted options not used: %d %d", db.Opt.PartitionNum, db.Opt.MemtableSize)}
+	it, _ := IndexTypeName(db.Opt.IndexType)
+	if it != "hash" {t.Errorf("error -- IndexType %s expected hash", it)}
+	valstr, err := db.GetVal("key1")
+	if err != nil || valstr != "val1" {t.Errorf("error -- GetVal: %s %v", valstr, err)}
+}
+
+func TestOpenFromConfigErrors(t *testing.T) {
+
+	dir := t.TempDir()
+	cfgPath := dir + "/cfg.yaml"
+
+	err := os.WriteFile(cfgPath, []byte("Partitions: 2\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	_, err = OpenFromConfig(cfgPath, false)
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- missing TableName: %v is not ErrConfig", err)}
+
+	err = os.WriteFile(cfgPath, []byte("TableName: CfgDat\nPartitions: -1\nIndexType: tree\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	_, err = OpenFromConfig(cfgPath, false)
+	var errs ConfigErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {t.Errorf("error -- invalid config: %v", err)}
+	_, err = os.Stat(dir + "/CfgDat")
+	if err == nil {t.Errorf("error -- table created for an invalid config")}
+
+	_, err = OpenFromConfig(dir + "/none.yaml", false)
+	if err == nil {t.Errorf("error -- OpenFromConfig of a missing file")}
+}
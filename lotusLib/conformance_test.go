@@ -0,0 +1,26 @@
+package lotusLib_test
+
+import (
+	"testing"
+
+	"github.com/prr123/lotusdb/lotusLib"
+	"github.com/prr123/lotusdb/lotusLib/lotusLibtest"
+)
+
+func TestLotusConformance(t *testing.T) {
+
+	lotusLibtest.RunConformance(t, func(dir string) (lotusLib.KVStore, error) {
+		return lotusLib.InitDb(dir, "Conformance", false)
+	})
+}
+
+func TestMemConformance(t *testing.T) {
+
+	suite := lotusLibtest.Suite{
+		Factory: func(dir string) (lotusLib.KVStore, error) {
+			return lotusLib.InitMemDb("Conformance", false)
+		},
+		Volatile: true,
+	}
+	suite.Run(t)
+}
@@ -0,0 +1,176 @@
+// csv
+// export and import of a table as csv or tsv
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// CSVOpt are the csv options of ExportCSV and ImportCSV.
+// A column is a header name or, without header, the 0-based index written as a number, e.g. "2".
+type CSVOpt struct {
+	// Comma is the delimiter; default ','. '\t' reads and writes tsv.
+	Comma rune
+	// Header: ImportCSV reads the column names from the first record, ExportCSV writes them.
+	Header bool
+	// KeyCol is the key column; default "0" or "key" with Header.
+	KeyCol string
+	// ValCols are the value columns; default "1" or "value" with Header.
+	// Several value columns are stored as a JSON object of the column names and values;
+	// ExportCSV writes the fields of such a value as columns.
+	ValCols []string
+}
+
+func (copt CSVOpt) defaults() CSVOpt {
+
+	if copt.Comma == 0 {copt.Comma = ','}
+	if len(copt.KeyCol) == 0 {
+		copt.KeyCol = "0"
+		if copt.Header {copt.KeyCol = "key"}
+	}
+	if len(copt.ValCols) == 0 {
+		copt.ValCols = []string{"1"}
+		if copt.Header {copt.ValCols = []string{"value"}}
+	}
+	return copt
+}
+
+// colIndex returns the index of column col in header.
+func colIndex(col string, header []string) (idx int, err error){
+
+	for i, nam := range header {
+		if nam == col {return i, nil}
+	}
+	idx, err = strconv.Atoi(col)
+	if err != nil || idx < 0 {return 0, fmt.Errorf("unknown column %q", col)}
+	return idx, nil
+}
+
+// ExportCSV writes every entry of the table, in key order, as a csv record to w.
+// The DumpOptions WithDumpPrefix and WithProgress apply. It returns the number of records written.
+func (dbp *DBObj) ExportCSV (w io.Writer, copt CSVOpt, opts ...DumpOption) (n int, err error){
+
+	opt := dumpOptions(opts)
+	copt = copt.defaults()
+
+	cw := csv.NewWriter(w)
+	cw.Comma = copt.Comma
+
+	if copt.Header {
+		err = cw.Write(append([]string{copt.KeyCol}, copt.ValCols...))
+		if err != nil {return 0, fmt.Errorf("ExportCSV: %w", err)}
+	}
+
+	it, err := dbp.NewIterator(WithPrefix(opt.Prefix), WithReverse(false))
+	if err != nil {return 0, err}
+	defer it.Close()
+
+	for it.Next() {
+		rec := []string{it.Key(), it.Value()}
+		if len(copt.ValCols) > 1 {
+			rec, err = splitValue(it.Key(), it.Value(), copt.ValCols)
+			if err != nil {return n, fmt.Errorf("ExportCSV: %w", err)}
+		}
+		err = cw.Write(rec)
+		if err != nil {return n, fmt.Errorf("ExportCSV: %w", err)}
+		n++
+		opt.progress(n, false)
+	}
+
+	cw.Flush()
+	err = cw.Error()
+	if err != nil {return n, fmt.Errorf("ExportCSV: %w", err)}
+	opt.progress(n, true)
+	return n, nil
+}
+
+// splitValue returns the record of key with the fields cols of the JSON object val.
+func splitValue(key, val string, cols []string) (rec []string, err error){
+
+	var obj map[string]json.RawMessage
+	err = json.Unmarshal([]byte(val), &obj)
+	if err != nil {return nil, fmt.Errorf("value of %s is not a JSON object: %w", key, err)}
+
+	rec = append(rec, key)
+	for _, col := range cols {
+		raw, ok := obj[col]
+		if !ok {
+			rec = append(rec, "")
+			continue
+		}
+		var str string
+		if json.Unmarshal(raw, &str) != nil {str = string(raw)}
+		rec = append(rec, str)
+	}
+	return rec, nil
+}
+
+// ImportCSV reads csv records from r and writes the entries in batches.
+// The DumpOptions WithDumpPrefix, WithBatchSize, WithPolicy and WithProgress apply.
+// It returns the number of entries written; the batches committed before an error are kept.
+func (dbp *DBObj) ImportCSV (r io.Reader, copt CSVOpt, opts ...DumpOption) (n int, err error){
+
+	opt := dumpOptions(opts)
+	copt = copt.defaults()
+
+	cr := csv.NewReader(r)
+	cr.Comma = copt.Comma
+	cr.FieldsPerRecord = -1
+
+	var header []string
+	if copt.Header {
+		header, err = cr.Read()
+		if err != nil {return 0, fmt.Errorf("ImportCSV header: %w", err)}
+	}
+
+	keyIdx, err := colIndex(copt.KeyCol, header)
+	if err != nil {return 0, fmt.Errorf("ImportCSV: %w", err)}
+	valIdx := make([]int, len(copt.ValCols))
+	for i, col := range copt.ValCols {
+		valIdx[i], err = colIndex(col, header)
+		if err != nil {return 0, fmt.Errorf("ImportCSV: %w", err)}
+	}
+
+	imp := newImporter(dbp, &opt)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {break}
+		if err != nil {return imp.n, fmt.Errorf("ImportCSV: %w", err)}
+		lin, _ := cr.FieldPos(0)
+
+		val, err := joinValue(rec, valIdx, copt.ValCols)
+		if err == nil && keyIdx >= len(rec) {err = fmt.Errorf("no key column %s", copt.KeyCol)}
+		if err == nil {err = imp.put(rec[keyIdx], val)}
+		if err != nil {return imp.n, fmt.Errorf("ImportCSV line %d: %w", lin, err)}
+	}
+
+	err = imp.commit()
+	if err != nil {return imp.n, fmt.Errorf("ImportCSV: %w", err)}
+	return imp.n, nil
+}
+
+// joinValue returns the value of the columns valIdx of rec; several columns are joined as a JSON object.
+func joinValue(rec []string, valIdx []int, cols []string) (val string, err error){
+
+	for i, idx := range valIdx {
+		if idx >= len(rec) {return "", fmt.Errorf("no value column %s", cols[i])}
+	}
+	if len(valIdx) == 1 {return rec[valIdx[0]], nil}
+
+	obj := make(map[string]string, len(valIdx))
+	for i, idx := range valIdx {
+		obj[cols[i]] = rec[idx]
+	}
+	valData, err := json.Marshal(obj)
+	if err != nil {return "", err}
+	return string(valData), nil
+}
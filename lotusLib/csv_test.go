@@ -0,0 +1,50 @@
+package lotusLib
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+
+	db, err := InitDb(t.TempDir(), "CsvDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+
+	data := "id,name,city,age\nu1,alice,Paris,31\nu2,\"bob, jr\",Oslo,40\n"
+	copt := CSVOpt{Header: true, KeyCol: "id", ValCols: []string{"name", "age"}}
+	n, err := db.ImportCSV(strings.NewReader(data), copt)
+	if err != nil || n != 2 {t.Fatalf("error -- ImportCSV: %d %v", n, err)}
+
+	valstr, err := db.GetVal("u2")
+	if err != nil || valstr != `{"age":"40","name":"bob, jr"}` {t.Errorf("error -- GetVal u2: %s %v", valstr, err)}
+
+	var buf bytes.Buffer
+	n, err = db.ExportCSV(&buf, copt)
+	if err != nil || n != 2 {t.Fatalf("error -- ExportCSV: %d %v", n, err)}
+	want := "id,name,age\nu1,alice,31\nu2,\"bob, jr\",40\n"
+	if buf.String() != want {t.Errorf("error -- ExportCSV:\n%s\nexpected\n%s", buf.String(), want)}
+
+	// tsv with one value column and no header
+	tsv := "k1\tv1\nk2\tv2\n"
+	n, err = db.ImportCSV(strings.NewReader(tsv), CSVOpt{Comma: '\t'})
+	if err != nil || n != 2 {t.Fatalf("error -- ImportCSV tsv: %d %v", n, err)}
+
+	buf.Reset()
+	_, err = db.ExportCSV(&buf, CSVOpt{Comma: '\t'}, WithDumpPrefix("k"))
+	if err != nil || buf.String() != tsv {t.Errorf("error -- ExportCSV tsv: %q %v", buf.String(), err)}
+
+	_, err = db.ImportCSV(strings.NewReader(tsv), CSVOpt{Comma: '\t'}, WithPolicy(FailExisting))
+	if !errors.Is(err, ErrKeyExists) {t.Errorf("error -- ImportCSV FailExisting: %v is not ErrKeyExists", err)}
+
+	_, err = db.ImportCSV(strings.NewReader(data), CSVOpt{Header: true, KeyCol: "id", ValCols: []string{"email"}})
+	if err == nil {t.Errorf("error -- ImportCSV with an unknown column")}
+	_, err = db.ImportCSV(strings.NewReader("a,1\nb\n"), CSVOpt{})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {t.Errorf("error -- ImportCSV of a short record: %v", err)}
+
+	// a value that is not a JSON object cannot be split into columns
+	_, err = db.ExportCSV(&buf, copt, WithDumpPrefix("k"))
+	if err == nil {t.Errorf("error -- ExportCSV of a value that is not JSON")}
+}
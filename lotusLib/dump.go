@@ -0,0 +1,215 @@
+// dump
+// export and import of a table as JSON Lines
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ImportPolicy decides what Import does with a key that exists in the table.
+type ImportPolicy int
+
+const (
+	// Overwrite replaces the value of an existing key.
+	Overwrite ImportPolicy = iota
+	// SkipExisting keeps the value of an existing key.
+	SkipExisting
+	// FailExisting stops the import with ErrKeyExists.
+	FailExisting
+)
+
+// DumpOpt are the options of Export and Import.
+type DumpOpt struct {
+	// Prefix selects the keys with the prefix.
+	Prefix string
+	// BatchSize is the number of entries Import commits in one batch; default 1000.
+	BatchSize int
+	// Policy decides what Import does with existing keys.
+	Policy ImportPolicy
+	// Progress is called with the number of entries written: by Export after every
+	// BatchSize entries and at the end, by Import after every committed batch.
+	Progress func(n int)
+}
+
+// DumpOption modifies the DumpOpt defaults.
+type DumpOption func(opt *DumpOpt)
+
+func WithDumpPrefix(prefix string) DumpOption {
+	return func(opt *DumpOpt) {opt.Prefix = prefix}
+}
+
+func WithBatchSize(size int) DumpOption {
+	return func(opt *DumpOpt) {opt.BatchSize = size}
+}
+
+func WithPolicy(policy ImportPolicy) DumpOption {
+	return func(opt *DumpOpt) {opt.Policy = policy}
+}
+
+func WithProgress(fn func(n int)) DumpOption {
+	return func(opt *DumpOpt) {opt.Progress = fn}
+}
+
+func dumpOptions(opts []DumpOption) (opt DumpOpt){
+
+	opt.BatchSize = 1000
+	for _, o := range opts {
+		o(&opt)
+	}
+	if opt.BatchSize <= 0 {opt.BatchSize = 1}
+	return opt
+}
+
+// progress reports n after every BatchSize entries and at the end, if final is set.
+// At the end n is only reported if it was not reported after the last entry.
+func (opt *DumpOpt) progress (n int, final bool) {
+	if opt.Progress == nil {return}
+	if n % opt.BatchSize == 0 {
+		if n > 0 && !final {opt.Progress(n)}
+		if n == 0 && final {opt.Progress(n)}
+		return
+	}
+	if final {opt.Progress(n)}
+}
+
+// dumpEntry is a line of a dump. Keys and values that are not valid utf-8
+// are written base64 encoded as key64 and value64.
+type dumpEntry struct {
+	Key string `json:"key,omitempty"`
+	Key64 []byte `json:"key64,omitempty"`
+	Val string `json:"value,omitempty"`
+	Val64 []byte `json:"value64,omitempty"`
+}
+
+func newDumpEntry(key, val string) (ent dumpEntry){
+
+	if utf8.ValidString(key) {
+		ent.Key = key
+	} else {
+		ent.Key64 = []byte(key)
+	}
+	if utf8.ValidString(val) {
+		ent.Val = val
+	} else {
+		ent.Val64 = []byte(val)
+	}
+	return ent
+}
+
+func (ent *dumpEntry) key() string {
+	if len(ent.Key64) > 0 {return string(ent.Key64)}
+	return ent.Key
+}
+
+func (ent *dumpEntry) val() string {
+	if len(ent.Val64) > 0 {return string(ent.Val64)}
+	return ent.Val
+}
+
+// Export writes every entry of the table, in key order, as a JSON Lines object
+// {"key":..., "value":...} to w. It returns the number of entries written.
+func (dbp *DBObj) Export (w io.Writer, opts ...DumpOption) (n int, err error){
+
+	opt := dumpOptions(opts)
+
+	it, err := dbp.NewIterator(WithPrefix(opt.Prefix), WithReverse(false))
+	if err != nil {return 0, err}
+	defer it.Close()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for it.Next() {
+		err = enc.Encode(newDumpEntry(it.Key(), it.Value()))
+		if err != nil {return n, fmt.Errorf("Export: %w", err)}
+		n++
+		opt.progress(n, false)
+	}
+
+	err = bw.Flush()
+	if err != nil {return n, fmt.Errorf("Export: %w", err)}
+	opt.progress(n, true)
+	return n, nil
+}
+
+// Import reads JSON Lines written by Export from r and writes the entries in batches
+// of BatchSize. Existing keys are handled by the Policy. It returns the number of entries written.
+// The batches committed before an error are kept.
+func (dbp *DBObj) Import (r io.Reader, opts ...DumpOption) (n int, err error){
+
+	opt := dumpOptions(opts)
+	br := bufio.NewReader(r)
+
+	imp := newImporter(dbp, &opt)
+	for lin := 1; ; lin++ {
+		line, rerr := br.ReadBytes('\n')
+		if rerr != nil && rerr != io.EOF {return imp.n, fmt.Errorf("Import: %w", rerr)}
+
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var ent dumpEntry
+			err = json.Unmarshal(line, &ent)
+			if err != nil {return imp.n, fmt.Errorf("Import line %d: %w", lin, err)}
+			err = imp.put(ent.key(), ent.val())
+			if err != nil {return imp.n, fmt.Errorf("Import line %d: %w", lin, err)}
+		}
+		if rerr == io.EOF {break}
+	}
+
+	err = imp.commit()
+	if err != nil {return imp.n, fmt.Errorf("Import: %w", err)}
+	return imp.n, nil
+}
+
+// importer writes entries in batches with the Policy and Prefix of opt.
+type importer struct {
+	dbp *DBObj
+	opt *DumpOpt
+	batch *BatchObj
+	n int
+}
+
+func newImporter(dbp *DBObj, opt *DumpOpt) (imp *importer){
+	return &importer{dbp: dbp, opt: opt, batch: dbp.NewBatch()}
+}
+
+func (imp *importer) put (key, val string) (err error){
+
+	if len(key) == 0 {return ErrKeyEmpty}
+	if !strings.HasPrefix(key, imp.opt.Prefix) {return nil}
+
+	if imp.opt.Policy != Overwrite {
+		exists, err := imp.batch.Exists([]byte(key))
+		if err != nil {return err}
+		if exists && imp.opt.Policy == SkipExisting {return nil}
+		if exists {return fmt.Errorf("%s: %w", key, ErrKeyExists)}
+	}
+
+	err = imp.batch.Put([]byte(key), []byte(val))
+	if err != nil {return err}
+
+	if imp.batch.Len() >= imp.opt.BatchSize {return imp.commit()}
+	return nil
+}
+
+// commit commits the batch and starts a new one.
+func (imp *importer) commit () (err error){
+
+	num := imp.batch.Len()
+	err = imp.batch.Commit()
+	if err != nil {return err}
+	imp.batch = imp.dbp.NewBatch()
+	if num == 0 {return nil}
+	imp.n += num
+	if imp.opt.Progress != nil {imp.opt.Progress(imp.n)}
+	return nil
+}
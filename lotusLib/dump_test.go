@@ -0,0 +1,71 @@
+package lotusLib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+
+	db, err := InitDb(t.TempDir(), "DumpDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+
+	entries := map[string]string{
+		"user:1": "alice",
+		"user:2": "",
+		"user:3": "bin\xff\x00",
+		"item:1": "pen",
+	}
+	for key, val := range entries {
+		err = db.AddEntry(key, val)
+		if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+	}
+
+	var buf bytes.Buffer
+	var prog []int
+	n, err := db.Export(&buf, WithDumpPrefix("user:"), WithBatchSize(2), WithProgress(func(n int) {prog = append(prog, n)}))
+	if err != nil || n != 3 {t.Fatalf("error -- Export: %d %v", n, err)}
+	if len(prog) != 2 || prog[1] != 3 {t.Errorf("error -- Export progress: %v", prog)}
+
+	// a multiple of BatchSize is reported once
+	prog = nil
+	_, err = db.Export(io.Discard, WithDumpPrefix("user:"), WithBatchSize(3), WithProgress(func(n int) {prog = append(prog, n)}))
+	if err != nil || fmt.Sprint(prog) != "[3]" {t.Errorf("error -- Export progress with 3 entries and BatchSize 3: %v %v", prog, err)}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != `{"key":"user:1","value":"alice"}` {t.Errorf("error -- Export lines: %q", lines)}
+	if !strings.Contains(lines[2], `"value64":`) {t.Errorf("error -- binary value not base64: %s", lines[2])}
+
+	db2, err := InitDb(t.TempDir(), "DumpDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db2.Close()
+
+	err = db2.AddEntry("user:1", "bob")
+	if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+
+	dump := buf.String()
+	_, err = db2.Import(strings.NewReader(dump), WithPolicy(FailExisting))
+	if !errors.Is(err, ErrKeyExists) {t.Errorf("error -- Import FailExisting: %v is not ErrKeyExists", err)}
+
+	n, err = db2.Import(strings.NewReader(dump), WithPolicy(SkipExisting))
+	if err != nil || n != 2 {t.Errorf("error -- Import SkipExisting: %d %v", n, err)}
+	valstr, _ := db2.GetVal("user:1")
+	if valstr != "bob" {t.Errorf("error -- SkipExisting overwrote user:1: %s", valstr)}
+
+	prog = nil
+	n, err = db2.Import(strings.NewReader(dump), WithBatchSize(2), WithProgress(func(n int) {prog = append(prog, n)}))
+	if err != nil || n != 3 {t.Errorf("error -- Import Overwrite: %d %v", n, err)}
+	if len(prog) != 2 || prog[1] != 3 {t.Errorf("error -- Import progress: %v", prog)}
+	for key, val := range entries {
+		if !strings.HasPrefix(key, "user:") {continue}
+		valstr, err = db2.GetVal(key)
+		if err != nil || valstr != val {t.Errorf("error -- GetVal %s after Import: %q %v expected %q", key, valstr, err, val)}
+	}
+
+	_, err = db2.Import(strings.NewReader("{\"key\":\"a\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {t.Errorf("error -- Import of invalid json: %v", err)}
+}
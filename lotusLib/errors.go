@@ -0,0 +1,77 @@
+// errors
+// error values returned by lotusLib
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// Errors returned by every KVStore backend. They are wrapped with %w,
+// so callers test them with errors.Is.
+var (
+	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyExists = errors.New("key exists")
+	ErrKeyEmpty = errors.New("key is empty")
+	ErrClosed = errors.New("database is closed")
+	ErrDbLocked = errors.New("database is locked by another process")
+	ErrReadOnly = errors.New("batch is read only")
+	ErrBatchDone = errors.New("batch is committed or rolled back")
+	ErrConfig = errors.New("invalid config")
+	ErrDrift = errors.New("options differ from the options the table was created with")
+	ErrNoManifest = errors.New("table has data but no manifest")
+	ErrTableNotFound = errors.New("table not found")
+	ErrTableExists = errors.New("table exists")
+	ErrTableOpen = errors.New("table is open")
+	ErrChecksum = errors.New("backup file does not match the backup manifest")
+)
+
+// ConfigError reports an invalid configuration field.
+// It matches ErrConfig with errors.Is.
+type ConfigError struct {
+	// Field is the yaml key of the option, e.g. "MemoryTableSize" or "Batch.Sync".
+	Field string
+	Reason string
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Err != nil {return fmt.Sprintf("config %s: %s: %v", e.Field, e.Reason, e.Err)}
+	return fmt.Sprintf("config %s: %s", e.Field, e.Reason)
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrConfig
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// dbErr wraps an error returned by lotusdb for operation op.
+// Known lotusdb errors are replaced by the matching lotusLib error.
+func dbErr(op string, err error) error {
+
+	switch {
+	case errors.Is(err, lotusdb.ErrKeyNotFound):
+		return fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+	case errors.Is(err, lotusdb.ErrKeyIsEmpty):
+		return fmt.Errorf("%s: %w", op, ErrKeyEmpty)
+	case errors.Is(err, lotusdb.ErrDBClosed):
+		return fmt.Errorf("%s: %w", op, ErrClosed)
+	case errors.Is(err, lotusdb.ErrDatabaseIsUsing):
+		return fmt.Errorf("%s: %w", op, ErrDbLocked)
+	case errors.Is(err, lotusdb.ErrReadOnlyBatch):
+		return fmt.Errorf("%s: %w", op, ErrReadOnly)
+	case errors.Is(err, lotusdb.ErrBatchCommitted):
+		return fmt.Errorf("%s: %w", op, ErrBatchDone)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,45 @@
+// kvStore
+// uniform interface for the kv engines wrapped by lotusLib
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+// KVStore is the engine independent interface of lotusLib.
+// Services should code against KVStore, so that the engine can be swapped
+// without touching the call sites. DBObj is the lotusdb backend.
+type KVStore interface {
+	// Get returns the value stored for key.
+	Get(key []byte) (val []byte, err error)
+
+	// Put stores val under key, overwriting any previous value.
+	Put(key, val []byte) (err error)
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key []byte) (err error)
+
+	// Exists reports whether key is stored.
+	Exists(key []byte) (res bool, err error)
+
+	// Iterate calls fn for every key/value pair selected by opt, in key order
+	// (or reverse key order). Iteration stops when fn returns false.
+	Iterate(opt IteratorOptions, fn func(key, val []byte) bool) (err error)
+
+	// Batch calls fn with a batch and commits all its writes atomically if fn returns nil.
+	// If fn returns an error, none of the writes are applied.
+	Batch(fn func(b KVBatch) error) (err error)
+
+	// Sync flushes all pending writes to stable storage.
+	Sync() (err error)
+
+	// Close releases the engine.
+	Close() (err error)
+}
+
+// KVBatch collects the writes of KVStore.Batch.
+type KVBatch interface {
+	Put(key, val []byte) (err error)
+	Delete(key []byte) (err error)
+}
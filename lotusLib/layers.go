@@ -0,0 +1,294 @@
+// layers
+// layered config: defaults, yaml file, environment variables and flags
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// Layer is the source of a config value.
+type Layer int
+
+const (
+	LayerDefault Layer = iota
+	// LayerPreset is a value set by the preset, which is selected by the key Preset of any layer.
+	LayerPreset
+	LayerFile
+	LayerEnv
+	LayerFlag
+)
+
+func (l Layer) String() string {
+	switch l {
+	case LayerDefault:
+		return "default"
+	case LayerPreset:
+		return "preset"
+	case LayerFile:
+		return "file"
+	case LayerEnv:
+		return "env"
+	case LayerFlag:
+		return "flag"
+	}
+	return fmt.Sprintf("Layer(%d)", int(l))
+}
+
+// ConfigValue is an effective config value and the layer that set it.
+type ConfigValue struct {
+	// Key is the yaml key, nested keys are joined with a dot, e.g. "Batch.Sync".
+	Key string
+	Value string
+	Layer Layer
+}
+
+// ConfigReport lists the effective config values in the order of LotusDbOption.
+type ConfigReport []ConfigValue
+
+func (rep ConfigReport) String() string {
+
+	var sb strings.Builder
+	for _, cv := range rep {
+		fmt.Fprintf(&sb, "%-20s %-12s %s\n", cv.Key, cv.Value, cv.Layer)
+	}
+	return sb.String()
+}
+
+// Layer returns the layer that set key.
+func (rep ConfigReport) Layer(key string) (l Layer, ok bool) {
+
+	for _, cv := range rep {
+		if cv.Key == key {return cv.Layer, true}
+	}
+	return LayerDefault, false
+}
+
+// ConfigLoader builds the options from the layers defaults, yaml file,
+// environment variables and flags; a later layer overrides an earlier one.
+// The keys of every layer are the yaml keys of LotusDbOption:
+// the environment variable of "Batch.Sync" is LOTUS_BATCH_SYNC,
+// the flag is -Batch.Sync.
+// The key Preset of the highest layer that sets it selects a preset, which is applied
+// on top of the defaults: the keys of every layer override it, and the values it sets
+// are reported as LayerPreset.
+type ConfigLoader struct {
+	// FilPath is the yaml config file; the file layer is skipped if FilPath is empty.
+	FilPath string
+	// EnvPrefix is the prefix of the environment variables; default LOTUS_.
+	EnvPrefix string
+	// LookupEnv reads an environment variable; default os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+
+	flags *flag.FlagSet
+	flagVals map[string]string
+}
+
+// optField is a leaf of LotusDbOption.
+type optField struct {
+	key string
+	val reflect.Value
+}
+
+// optFields returns the leaves of optObj with their yaml keys.
+func optFields(optObj *LotusDbOption) (fields []optField) {
+	return appendFields(fields, "", reflect.ValueOf(optObj).Elem())
+}
+
+func appendFields(fields []optField, prefix string, v reflect.Value) []optField {
+
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		key := prefix + strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct && sf.Type.Name() != "Duration" {
+			fields = appendFields(fields, key + ".", fv)
+			continue
+		}
+		fields = append(fields, optField{key: key, val: fv})
+	}
+	return fields
+}
+
+// set parses str like a yaml value and sets the field.
+func (of optField) set(str string) (err error) {
+
+	if of.val.Kind() == reflect.String {
+		of.val.SetString(str)
+		return nil
+	}
+	ptr := reflect.New(of.val.Type())
+	err = yaml.Unmarshal([]byte(str), ptr.Interface())
+	if err != nil {return err}
+	of.val.Set(ptr.Elem())
+	return nil
+}
+
+// EnvName returns the environment variable of a yaml key.
+func (cl *ConfigLoader) EnvName(key string) string {
+
+	prefix := cl.EnvPrefix
+	if len(prefix) == 0 {prefix = "LOTUS_"}
+	return prefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+type flagVal struct {
+	key string
+	vals map[string]string
+}
+
+func (fv *flagVal) String() string {
+	if fv.vals == nil {return ""}
+	return fv.vals[fv.key]
+}
+
+func (fv *flagVal) Set(str string) error {
+	fv.vals[fv.key] = str
+	return nil
+}
+
+// BindFlags adds a flag for every yaml key to fs.
+// Only the flags that are set on the command line override the other layers.
+func (cl *ConfigLoader) BindFlags(fs *flag.FlagSet) {
+
+	var optObj LotusDbOption
+	cl.flags = fs
+	cl.flagVals = make(map[string]string)
+	for _, of := range optFields(&optObj) {
+		fs.Var(&flagVal{key: of.key, vals: cl.flagVals}, of.key, "config " + of.key + " (env " + cl.EnvName(of.key) + ")")
+	}
+}
+
+// Load builds the options from the layers. It returns the options with a report
+// of the layer that set each value. Invalid values of every layer are listed in ConfigErrors.
+func (cl *ConfigLoader) Load() (optObj LotusDbOption, rep ConfigReport, err error) {
+
+	var errs ConfigErrors
+
+	db := newDb("", "", false)
+	optObj, err = db.LotusDbOption()
+	if err != nil {return optObj, nil, err}
+
+	fields := optFields(&optObj)
+	layers := make([]Layer, len(fields))
+	fieldIdx := make(map[string]int)
+	for i, of := range fields {
+		fieldIdx[of.key] = i
+	}
+
+	// every layer is read before the values are set, since the preset of the highest layer comes first
+	var yamlData []byte
+	fileKeys := make(map[string]bool)
+	preset, presetLayer := "", LayerDefault
+	if len(cl.FilPath) > 0 {
+		yamlData, err = os.ReadFile(cl.FilPath)
+		if err != nil {return optObj, nil, fmt.Errorf("ReadFile: %w", err)}
+		var keyMap map[string]any
+		err = yaml.Unmarshal(yamlData, &keyMap)
+		if err != nil {return optObj, nil, &ConfigError{Field: cl.FilPath, Reason: "cannot unmarshal yaml", Err: err}}
+		flattenKeys(fileKeys, "", keyMap)
+		if fileKeys["Preset"] {preset, presetLayer = fmt.Sprint(keyMap["Preset"]), LayerFile}
+	}
+
+	lookup := cl.LookupEnv
+	if lookup == nil {lookup = os.LookupEnv}
+	envVals := make(map[string]string)
+	for _, of := range fields {
+		str, ok := lookup(cl.EnvName(of.key))
+		if ok {envVals[of.key] = str}
+	}
+	if str, ok := envVals["Preset"]; ok {preset, presetLayer = str, LayerEnv}
+
+	setFlags := make(map[string]bool)
+	if cl.flags != nil {
+		cl.flags.Visit(func(f *flag.Flag) {setFlags[f.Name] = true})
+	}
+	if setFlags["Preset"] {preset, presetLayer = cl.flagVals["Preset"], LayerFlag}
+
+	if len(preset) > 0 {
+		err = optObj.ApplyPreset(preset)
+		if err != nil {
+			errs.addErr("Preset", err)
+		} else {
+			for _, key := range presets[preset].Keys {layers[fieldIdx[key]] = LayerPreset}
+		}
+		layers[fieldIdx["Preset"]] = presetLayer
+	}
+
+	if len(cl.FilPath) > 0 {
+		err = unmarshalKeys(cl.FilPath, yamlData, &optObj)
+		var keyErrs ConfigErrors
+		if err != nil && !errors.As(err, &keyErrs) {return optObj, nil, err}
+		errs = append(errs, keyErrs...)
+		optObj.Preset = preset
+
+		for i, of := range fields {
+			if fileKeys[of.key] && of.key != "Preset" {layers[i] = LayerFile}
+		}
+		if len(optObj.DirPath) == 0 && !fileKeys["DirPath"] {
+			optObj.DirPath = filepath.Dir(cl.FilPath)
+			layers[fieldIdx["DirPath"]] = LayerFile
+		}
+	}
+
+	for i, of := range fields {
+		str, ok := envVals[of.key]
+		if !ok || of.key == "Preset" {continue}
+		err = of.set(str)
+		if err != nil {
+			errs.add(of.key, fmt.Sprintf("%s=%q: %v", cl.EnvName(of.key), str, err))
+			continue
+		}
+		layers[i] = LayerEnv
+	}
+
+	for i, of := range fields {
+		if !setFlags[of.key] || of.key == "Preset" {continue}
+		str := cl.flagVals[of.key]
+		err = of.set(str)
+		if err != nil {
+			errs.add(of.key, fmt.Sprintf("-%s=%q: %v", of.key, str, err))
+			continue
+		}
+		layers[i] = LayerFlag
+	}
+
+	rep = make(ConfigReport, len(fields))
+	for i, of := range fields {
+		rep[i] = ConfigValue{Key: of.key, Value: fmt.Sprint(of.val.Interface()), Layer: layers[i]}
+	}
+
+	return optObj, rep, errs.err()
+}
+
+func flattenKeys(keys map[string]bool, prefix string, keyMap map[string]any) {
+
+	for key, val := range keyMap {
+		keys[prefix + key] = true
+		sub, ok := val.(map[string]any)
+		if ok {flattenKeys(keys, prefix + key + ".", sub)}
+	}
+}
+
+// Open loads the layers and opens the table. TableName and DirPath are required;
+// without a DirPath the directory of the config file is used.
+func (cl *ConfigLoader) Open(dbg bool, opts ...InitOption) (dbpt *DBObj, rep ConfigReport, err error) {
+
+	optObj, rep, err := cl.Load()
+	if err != nil {return nil, rep, err}
+
+	db, err := OpenOption(&optObj, dbg, opts...)
+	return db, rep, err
+}
@@ -0,0 +1,81 @@
+package lotusLib
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfigLoader(t *testing.T) {
+
+	dir := t.TempDir()
+	cfgPath := dir + "/cfg.yaml"
+	cfg := "TableName: LayerDat\nPartitions: 2\nMemoryTableSize: 32MiB\nBatch:\n  Sync: true\n"
+	err := os.WriteFile(cfgPath, []byte(cfg), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	env := map[string]string{
+		"LOTUS_PARTITIONS": "4",
+		"LOTUS_FLUSHWAITTIME": "250ms",
+		"LOTUS_WRITEOPT_DISABLEWAL": "true",
+	}
+	cl := ConfigLoader{
+		FilPath: cfgPath,
+		LookupEnv: func(key string) (string, bool) {val, ok := env[key]; return val, ok},
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cl.BindFlags(fs)
+	err = fs.Parse([]string{"-Partitions=8", "-InterOpt.Prefix=user:"})
+	if err != nil {t.Fatalf("error -- flag Parse: %v", err)}
+
+	optObj, rep, err := cl.Load()
+	if err != nil {t.Fatalf("error -- Load: %v", err)}
+
+	if optObj.DirPath != dir {t.Errorf("error -- DirPath %s expected %s", optObj.DirPath, dir)}
+	if optObj.PartitionNum != 8 {t.Errorf("error -- Partitions %d expected 8", optObj.PartitionNum)}
+	if optObj.MemtableSize != 32 << 20 {t.Errorf("error -- MemoryTableSize %s", optObj.MemtableSize)}
+	if optObj.WaitMemSpaceTimeout != 250 * time.Millisecond {t.Errorf("error -- FlushWaitTime %s", optObj.WaitMemSpaceTimeout)}
+	if !optObj.Batch.Sync || !optObj.Write.DisableWal || optObj.IterOpt.Prefix != "user:" {t.Errorf("error -- nested options: %+v", optObj)}
+
+	layers := map[string]Layer{
+		"TableName": LayerFile,
+		"MemoryTableSize": LayerFile,
+		"Batch.Sync": LayerFile,
+		"Partitions": LayerFlag,
+		"FlushWaitTime": LayerEnv,
+		"WriteOpt.DisableWAL": LayerEnv,
+		"InterOpt.Prefix": LayerFlag,
+		"IndexType": LayerDefault,
+	}
+	for key, want := range layers {
+		l, ok := rep.Layer(key)
+		if !ok || l != want {t.Errorf("error -- %s set by %s expected %s", key, l, want)}
+	}
+
+	db, _, err := cl.Open(false)
+	if err != nil {t.Fatalf("error -- Open: %v", err)}
+	defer db.Close()
+	if db.Opt.PartitionNum != 8 || db.TabNam != "LayerDat" {t.Errorf("error -- Open options: %d %s", db.Opt.PartitionNum, db.TabNam)}
+}
+
+func TestConfigLoaderErrors(t *testing.T) {
+
+	env := map[string]string{
+		"APP_PARTITIONS": "many",
+		"APP_SYNCSIZE": "1X",
+	}
+	cl := ConfigLoader{
+		EnvPrefix: "APP_",
+		LookupEnv: func(key string) (string, bool) {val, ok := env[key]; return val, ok},
+	}
+	_, _, err := cl.Load()
+	var errs ConfigErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {t.Errorf("error -- invalid env values: %v", err)}
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- %v is not ErrConfig", err)}
+
+	cl.LookupEnv = func(key string) (string, bool) {return "", false}
+	_, _, err = cl.Open(false)
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- Open without DirPath: %v is not ErrConfig", err)}
+}
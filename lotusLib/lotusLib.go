@@ -8,11 +8,13 @@
 package lotusLib
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"time"
 	"os"
+	"strings"
+	"sync"
 //	"unsafe"
 //	"sort"
 
@@ -26,10 +28,19 @@ type DBObj struct {
 	TabNam string
 	Dbg bool
 	Opt lotusdb.Options
-	Batch lotusdb.BatchOptions
+	BatchOpt lotusdb.BatchOptions
 	Write lotusdb.WriteOptions
 	IterOpt lotusdb.IteratorOptions
 	Db *lotusdb.DB
+	// hashNam is the registered name of Opt.KeyHashFunction. It is kept when the function
+	// is set, since different closures of one func literal cannot be told apart.
+	hashNam string
+	// migrate allows open to migrate a table created with other options; see WithMigration.
+	migrate bool
+	// wrMu pauses the writes during Backup and while InsEntry or UpdEntry checks a key.
+	wrMu sync.RWMutex
+	// optMu guards BatchOpt, Write and IterOpt, which a Watcher changes on an open table.
+	optMu sync.RWMutex
 }
 
 type hash struct {
@@ -41,13 +52,17 @@ type LotusDbOption struct {
 	// DirPath specifies the directory path where all the database files will be stored.
 	DirPath string `yaml:"DirPath"`
 
-	TabNam string `yam:"TableName"`
+	// TabNam is the table; the lotusdb files are stored in DirPath/TabNam.
+	TabNam string `yaml:"TableName"`
+
+	// Preset names a preset that sets the options before the other keys of the file;
+	// keys in the file override the preset.
+	Preset string `yaml:"Preset,omitempty"`
 
 	// MemtableSize represents the maximum size in bytes for a memtable.
 	// It means that each memtable will occupy so much memory.
-	// Default value is 64MB.
-//	MemtableSize uint32 `yaml:"MemoryTableSize"`
-	MemtableSize string `yaml:"MemoryTableSize"`
+	// Default value is 64MiB.
+	MemtableSize Size `yaml:"MemoryTableSize"`
 
 	// MemtableNums represents maximum number of memtables to keep in memory before flushing.
 	// Default value is 15.
@@ -56,8 +71,7 @@ type LotusDbOption struct {
 	// BlockCache specifies the size of the block cache in number of bytes.
 	// A block cache is used to store recently accessed data blocks, improving read performance.
 	// If BlockCache is set to 0, no block cache will be used.
-//	BlockCache uint32
-	BlockCache string `yaml:"BlockCache"`
+	BlockCache Size `yaml:"BlockCache"`
 
 	// Sync is whether to synchronize writes through os buffer cache and down onto the actual disk.
 	// Setting sync is required for durability of a single write operation, but also results in slower writes.
@@ -70,25 +84,22 @@ type LotusDbOption struct {
 	Sync bool `yaml:"Sync"`
 
 	// BytesPerSync specifies the number of bytes to write before calling fsync.
-//	BytesPerSync uint32
-	BytesPerSyncStr string `yaml:"SyncSize"`
+	BytesPerSync Size `yaml:"SyncSize"`
 
 	// PartitionNum specifies the number of partitions to use for the index and value log.
 	PartitionNum int `yaml:"Partitions"`
 
-	// KeyHashFunction specifies the hash function for sharding.
+	// KeyHashFunction specifies the hash function for sharding by its registered name.
 	// It is used to determine which partition a key belongs to.
-	// Default value is xxhash.
-//	KeyHashFunction func([]byte) uint64
+	// Default value is xxhash; see RegisterHashFunc.
+	KeyHashFunction string `yaml:"KeyHashFunction"`
 
 	// ValueLogFileSize size of a single value log file.
-	// Default value is 1GB.
-//	ValueLogFileSize int64
-	ValueLogFileSize string `yaml:"VLogSize"`
+	// Default value is 1GiB.
+	ValueLogFileSize Size `yaml:"VLogSize"`
 
-	// indexType.
-	// default value is bptree.
-//	IndexType IndexType
+	// IndexType is btree or hash.
+	// Default value is btree.
 	IndexType string `yaml:"IndexType"`
 
 
@@ -153,180 +164,381 @@ type IteratorOptions struct {
 
 
 
-func InitDb(dirPath, tabNam string, dbg bool) (dbpt *DBObj, err error){
+// InitDb opens the table tabNam in dirPath.
+// A table opened before is reopened with the effective config kept in the table directory;
+// a new table is created with the lotusdb defaults.
+// The options opts, e.g. WithPreset, are applied after the config.
+func InitDb(dirPath, tabNam string, dbg bool, opts ...InitOption) (dbpt *DBObj, err error){
+
+	db := newDb(dirPath, tabNam, dbg)
+
+	cfgPath := db.Opt.DirPath + "/" + ConfigFilNam
+	_, err = os.Stat(cfgPath)
+	if err == nil {
+		err = db.loadOptionFile(cfgPath)
+		if err != nil {return nil, err}
+		// the table directory may have been moved since the config was written
+		db.Opt.DirPath = dirPath + "/" + tabNam
+	}
+
+	for _, opt := range opts {
+		err = opt(db)
+		if err != nil {return nil, err}
+	}
+
+	err = db.open()
+	if err != nil {return nil, err}
+
+	return db, nil
+}
+
+func newDb(dirPath, tabNam string, dbg bool) (dbpt *DBObj){
 
 	db := DBObj {
 		Opt: lotusdb.DefaultOptions,
 		Dbg: dbg,
+		hashNam: "xxhash",
 	}
 
 	db.Opt.DirPath = dirPath + "/" +tabNam
 	db.DirPath = dirPath
 	db.TabNam = tabNam
 
-	options := db.Opt
+	return &db
+}
+
+// open applies the defaults, validates the options and opens lotusdb.
+// The effective config is written to ConfigFilNam in the table directory.
+func (dbpt *DBObj) open () (err error){
+
+	dbpt.ApplyDefaults()
+	err = dbpt.ValidateOpts()
+	if err != nil {return err}
 
-    ldb, err := lotusdb.Open(options)
-    if err != nil { log.Fatalf("lotusdb.Open: %v", err)}
-	db.Db = ldb
+	err = dbpt.checkManifest()
+	if err != nil {return err}
 
-	dbp := &db
+	options := dbpt.Opt
+
+	ldb, err := lotusdb.Open(options)
+	if err != nil {return dbErr("lotusdb.Open " + options.DirPath, err)}
+	dbpt.Db = ldb
+
+	err = dbpt.saveOptionFile(options.DirPath + "/" + ConfigFilNam)
+	if err != nil {
+		ldb.Close()
+		dbpt.Db = nil
+		return fmt.Errorf("could not save effective config: %w", err)
+	}
 
-	return dbp, nil
+	err = dbpt.writeManifest()
+	if err != nil {
+		ldb.Close()
+		dbpt.Db = nil
+		return err
+	}
+	return nil
 }
 
+// DBObj is the lotusdb backend of KVStore
+var _ KVStore = (*DBObj)(nil)
+
 func (dbpt *DBObj) Close () (err error){
-	ldb := *dbpt.Db
-	err = ldb.Close()
-	return err
+	err = dbpt.Db.Close()
+	if err != nil {return dbErr("Close", err)}
+	return nil
 }
 
-func (dbpt *DBObj) LoadOption (filNam string) (err error){
-
-	yamlFilPath := (*dbpt).DirPath + "/" + filNam
-//	log.Printf("yaml path: %s\n", yamlFilPath)
+func (dbp *DBObj) Get (key []byte) (val []byte, err error){
 
-	yamlData, err := os.ReadFile(yamlFilPath)
-	if err != nil {return fmt.Errorf("ReadFile %v\n", err)}
+	db := (*dbp).Db
+	val, err = db.Get(key)
+	if err != nil {return nil, dbErr("Get", err)}
 
-	optObj := LotusDbOption {}
+	return val, nil
+}
 
-	err = yaml.Unmarshal(yamlData, &optObj)
-	if err != nil {return fmt.Errorf("UnMarshal %v\n", err)}
+// WriteOption overrides the Write options of the table for a single write.
+type WriteOption func(opt *lotusdb.WriteOptions)
 
-//	log.Printf("load optData: %v\n", optObj)
+// WithSync forces an fsync of the write.
+func WithSync() WriteOption {
+	return func(opt *lotusdb.WriteOptions) {opt.Sync = true}
+}
 
-	opt := lotusdb.Options{}
+// WithoutWAL skips the write ahead log; the write may get lost after a crash.
+func WithoutWAL() WriteOption {
+	return func(opt *lotusdb.WriteOptions) {opt.DisableWal = true}
+}
 
-	opt.DirPath = optObj.DirPath + "/" + optObj.TabNam
+// writeOptions returns the Write options of the table modified by opts.
+func (dbp *DBObj) writeOptions (opts []WriteOption) (wopt *lotusdb.WriteOptions){
 
-	var itmp uint32
-	_, err = fmt.Sscanf(optObj.MemtableSize,"%d", &itmp)
-	if err != nil {return fmt.Errorf("MemtableSize %v", err)}
-	opt.MemtableSize = itmp
-	opt.MemtableNums = optObj.MemtableNums
+	dbp.optMu.RLock()
+	wo := dbp.Write
+	dbp.optMu.RUnlock()
+	for _, o := range opts {
+		o(&wo)
+	}
+	return &wo
+}
 
-	_, err = fmt.Sscanf(optObj.BlockCache,"%d", &itmp)
-	if err != nil {return fmt.Errorf("BlockCache %v", err)}
-	opt.BlockCache = itmp
+func (dbp *DBObj) Put (key, val []byte) (err error){
 
-	opt.Sync = optObj.Sync
+	return dbp.put(key, val, nil)
+}
 
-	_, err = fmt.Sscanf(optObj.BytesPerSyncStr,"%d", &itmp)
-    if err != nil {return fmt.Errorf("BytesPerSync %v", err)}
-    opt.BytesPerSync = itmp
+func (dbp *DBObj) put (key, val []byte, opts []WriteOption) (err error){
 
-	opt.PartitionNum = optObj.PartitionNum
+	dbp.wrMu.RLock()
+	defer dbp.wrMu.RUnlock()
+	return dbp.putLocked(key, val, opts)
+}
 
-	var itmp64 int64
-	_, err = fmt.Sscanf(optObj.ValueLogFileSize,"%d", &itmp64)
-    if err != nil {return fmt.Errorf("ValueLogFileSize %v", err)}
-    opt.ValueLogFileSize = itmp64
+// putLocked writes key; the caller holds wrMu.
+func (dbp *DBObj) putLocked (key, val []byte, opts []WriteOption) (err error){
 
-//	opt.IndexType = optObj.IndexType
+	db := (*dbp).Db
+	err = db.Put(key, val, dbp.writeOptions(opts))
+	if err != nil {return dbErr("Put", err)}
 
-	opt.CompactBatchCount = optObj.CompactBatchCount
+	return nil
+}
 
-	(*dbpt).Opt = opt
+func (dbp *DBObj) Delete (key []byte) (err error){
 
-//	opt.WaitMemSpaceTimeout = optObj.WaitMemSpaceTimeout
+	return dbp.del(key, nil)
+}
 
-//	fmt.Printf("optObj.Batch: %v\n", optObj.Batch)
-	(*dbpt).Batch.Sync = optObj.Batch.Sync
-	(*dbpt).Batch.ReadOnly = optObj.Batch.ReadOnly
+func (dbp *DBObj) del (key []byte, opts []WriteOption) (err error){
 
-	(*dbpt).Write.Sync = optObj.Write.Sync
-	(*dbpt).Write.DisableWal = optObj.Write.DisableWal
+	dbp.wrMu.RLock()
+	defer dbp.wrMu.RUnlock()
+	db := (*dbp).Db
+	err = db.Delete(key, dbp.writeOptions(opts))
+	if err != nil {return dbErr("Delete", err)}
 
-	(*dbpt).IterOpt.Prefix = []byte(optObj.IterOpt.Prefix)
-	(*dbpt).IterOpt.Reverse = optObj.IterOpt.Reverse
 	return nil
 }
 
-func (dbpt *DBObj) SaveOption (filNam string) (err error){
+func (dbp *DBObj) Exists (key []byte) (res bool, err error){
 
-	opt := (*dbpt).Opt
+	db := (*dbp).Db
+	res, err = db.Exist(key)
+	if err != nil {return false, dbErr("Exist", err)}
 
-	yamlFilPath := (*dbpt).DirPath + "/" + filNam
-	//log.Printf("yaml path: %s\n", yamlFilPath)
+	return res, nil
+}
 
-	lotOpt := LotusDbOption{}
+// Iterate walks the keys selected by opt with a lotusdb iterator.
+func (dbp *DBObj) Iterate (opt IteratorOptions, fn func(key, val []byte) bool) (err error){
 
-	lotOpt.DirPath = (*dbpt).DirPath
-	lotOpt.TabNam = (*dbpt).TabNam
+	db := (*dbp).Db
+	iterOpt := lotusdb.IteratorOptions{
+		Prefix: []byte(opt.Prefix),
+		Reverse: opt.Reverse,
+	}
+	iter, err := db.NewIterator(iterOpt)
+	if err != nil {return dbErr("NewIterator", err)}
+	defer iter.Close()
 
-	// todo parse number to allow use of K, M and G for 1000, 1,000,000 and 1,000,000,000
-	lotOpt.MemtableSize = fmt.Sprintf("%d",opt.MemtableSize)
-	lotOpt.MemtableNums = opt.MemtableNums
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		if !fn(iter.Key(), iter.Value()) {break}
+	}
+	return nil
+}
 
-	lotOpt.BlockCache = fmt.Sprintf("%d",opt.BlockCache)
-	lotOpt.Sync = opt.Sync
+// Batch collects the writes of fn in a BatchObj using the Batch options.
+// The batch is only committed if fn succeeds.
+func (dbp *DBObj) Batch (fn func(b KVBatch) error) (err error){
 
-	lotOpt.BytesPerSyncStr = fmt.Sprintf("%d",opt.BytesPerSync)
-	lotOpt.PartitionNum = opt.PartitionNum
+	batch := dbp.NewBatch()
+	err = fn(batch)
+	if err != nil {
+		batch.Rollback()
+		return err
+	}
 
-	lotOpt.ValueLogFileSize = fmt.Sprintf("%d",opt.ValueLogFileSize)
-//	lotOpt.IndexType = fmt.Sprintf("%d",opt.IndexType)
-	lotOpt.CompactBatchCount = opt.CompactBatchCount
-//	lotOpt.WaitMemSpaceTimeout = opt.WaitMemSpaceTimeout
+	return batch.Commit()
+}
 
-	lotOpt.Batch.Sync = (*dbpt).Batch.Sync
-	lotOpt.Batch.ReadOnly = (*dbpt).Batch.ReadOnly
+func (dbp *DBObj) Sync () (err error){
 
-	lotOpt.Write.Sync = (*dbpt).Write.Sync
-	lotOpt.Write.DisableWal = (*dbpt).Write.DisableWal
+	db := (*dbp).Db
+	err = db.Sync()
+	if err != nil {return dbErr("Sync", err)}
+	return nil
+}
 
-	lotOpt.IterOpt.Prefix = string((*dbpt).IterOpt.Prefix)
-	lotOpt.IterOpt.Reverse = (*dbpt).IterOpt.Reverse
+// Compact rewrites the value log without the deleted and overwritten values.
+// Like a write, it waits for a running Backup.
+func (dbp *DBObj) Compact () (err error){
 
+	dbp.wrMu.RLock()
+	defer dbp.wrMu.RUnlock()
+	db := (*dbp).Db
+	err = db.Compact()
+	if err != nil {return dbErr("Compact", err)}
+	return nil
+}
 
-	optData, err := yaml.Marshal(lotOpt)
-	if err != nil {return fmt.Errorf("Marshal %v\n", err)}
+// LoadOption reads the options from the yaml file filNam in DirPath.
+// Keys missing in the file keep their current value.
+// The options are validated; if any field is invalid, ConfigErrors lists them
+// all and the table options are not changed.
+// LoadOption does not reopen an open table; use OpenFromConfig to open a table with a config.
+func (dbpt *DBObj) LoadOption (filNam string) (err error){
 
-	//log.Printf("*** optData:\n%s\n", string(optData))
+	return dbpt.loadOptionFile((*dbpt).DirPath + "/" + filNam)
+}
 
-	err = os.WriteFile(yamlFilPath, optData, 0666)
-	if err != nil {return fmt.Errorf("WriteFile %v\n", err)}
+func (dbpt *DBObj) loadOptionFile (yamlFilPath string) (err error){
 
-	return err
-}
+	optObj, err := dbpt.LotusDbOption()
+	if err != nil {return err}
 
+	// keys with invalid values are reported with the validation errors
+	err = readOption(yamlFilPath, &optObj)
+	var keyErrs ConfigErrors
+	if err != nil && !errors.As(err, &keyErrs) {return err}
 
+	return dbpt.setOption(&optObj, keyErrs...)
+}
+
+// readOption reads the yaml file into optObj; keys missing in the file keep their value.
+func readOption (yamlFilPath string, optObj *LotusDbOption) (err error){
 
-func (dbpt *DBObj) ValidateOpts() error {
+	yamlData, err := os.ReadFile(yamlFilPath)
+	if err != nil {return fmt.Errorf("ReadFile: %w", err)}
 
-	options := (*dbpt).Opt
+	return unmarshalOption(yamlFilPath, yamlData, optObj)
+}
 
-//	if options.IndexType == Hash {
-//		return errors.New("hash index is not supported yet")
-//	}
+// unmarshalOption applies the preset of the yaml data and then the keys of the data.
+// Keys with invalid values keep their value and are returned as ConfigErrors.
+func unmarshalOption (yamlFilPath string, yamlData []byte, optObj *LotusDbOption) (err error){
 
-	if options.DirPath == "" {
-		return fmt.Errorf("the database directory path cannot be empty")
+	var presetObj struct {
+		Preset string `yaml:"Preset"`
 	}
-	if options.MemtableSize <= 0 {
-		log.Printf("MemtableSize is 0 resetting to 64\n")
-		options.MemtableSize = 64 << 20 // 64MB
+	err = yaml.Unmarshal(yamlData, &presetObj)
+	if err != nil {return &ConfigError{Field: yamlFilPath, Reason: "cannot unmarshal yaml", Err: err}}
+	if len(presetObj.Preset) > 0 {
+		err = optObj.ApplyPreset(presetObj.Preset)
+		if err != nil {return err}
 	}
-	if options.MemtableNums <= 0 {
-		log.Printf("MemtableNums is 0 resetting to 15\n")
-		options.MemtableNums = 15
+
+	return unmarshalKeys(yamlFilPath, yamlData, optObj)
+}
+
+// unmarshalKeys sets the keys of the yaml data without applying its preset.
+// Keys with invalid values keep their value and are returned as ConfigErrors.
+func unmarshalKeys (yamlFilPath string, yamlData []byte, optObj *LotusDbOption) (err error){
+
+	err = yaml.Unmarshal(yamlData, optObj)
+	if err != nil {return decodeKeys(yamlFilPath, yamlData, optObj, err)}
+	return nil
+}
+
+// decodeKeys sets the keys of the yaml data one at a time, so that every key with an
+// invalid value is reported by its yaml path. yamlErr is the error of the whole data;
+// it is returned if no single key fails.
+func decodeKeys (yamlFilPath string, yamlData []byte, optObj *LotusDbOption, yamlErr error) (err error){
+
+	fileErr := &ConfigError{Field: yamlFilPath, Reason: "cannot unmarshal yaml", Err: yamlErr}
+
+	var keyMap map[string]any
+	err = yaml.Unmarshal(yamlData, &keyMap)
+	if err != nil {return fileErr}
+
+	var errs ConfigErrors
+	for _, of := range optFields(optObj) {
+		val, ok := lookupKey(keyMap, of.key)
+		if !ok {continue}
+
+		str, ok := val.(string)
+		if !ok {
+			valData, err := yaml.Marshal(val)
+			if err != nil {errs.addErr(of.key, err); continue}
+			str = strings.TrimSpace(string(valData))
+		}
+		err = of.set(str)
+		if err != nil {errs.add(of.key, fmt.Sprintf("invalid value %q: %s", str, yamlErrMsg(err)))}
 	}
-	if options.PartitionNum <= 0 {
-		log.Printf("PartitionNum is 0 resetting to 5\n")
-		options.PartitionNum = 5
+	if len(errs) == 0 {return fileErr}
+	return errs
+}
+
+// yamlErrMsg returns the message of a yaml error without its position and source excerpt.
+func yamlErrMsg (err error) string {
+
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	if strings.HasPrefix(msg, "[") {
+		if _, rest, ok := strings.Cut(msg, "] "); ok {msg = rest}
 	}
-	if options.ValueLogFileSize <= 0 {
-		log.Printf("ValueLogFileSize is 0 resetting to 1GB\n")
-		options.ValueLogFileSize = 1 << 30 // 1GB
+	return msg
+}
+
+// lookupKey returns the value of the yaml path key, e.g. "Batch.Sync", in keyMap.
+func lookupKey (keyMap map[string]any, key string) (val any, ok bool){
+
+	keys := strings.Split(key, ".")
+	for i, k := range keys {
+		val, ok = keyMap[k]
+		if !ok || i == len(keys) - 1 {return val, ok}
+		keyMap, ok = val.(map[string]any)
+		if !ok {return nil, false}
 	}
+	return nil, false
+}
+
+// setOption validates optObj and sets the table options from it.
+// keyErrs are the keys of the config file that could not be decoded; they are
+// reported with the validation errors and the options are not set.
+func (dbpt *DBObj) setOption (optObj *LotusDbOption, keyErrs ...*ConfigError) (err error){
+
+	errs := append(ConfigErrors(nil), keyErrs...)
+	opt, batch, write, iterOpt, err := optObj.Options()
+	if err != nil {errs.merge(err)}
+
+	cand := DBObj{DirPath: dbpt.DirPath, TabNam: dbpt.TabNam, Opt: opt, hashNam: optObj.KeyHashFunction}
+	err = cand.ValidateOpts()
+	if err != nil {errs.merge(err)}
+	if len(errs) > 0 {return errs}
+
+	dbpt.optMu.Lock()
+	(*dbpt).Opt = opt
+	(*dbpt).hashNam = optObj.KeyHashFunction
+	(*dbpt).BatchOpt = batch
+	(*dbpt).Write = write
+	(*dbpt).IterOpt = iterOpt
+	dbpt.optMu.Unlock()
+	return nil
+}
+
+// SaveOption writes the options as yaml file filNam in DirPath.
+func (dbpt *DBObj) SaveOption (filNam string) (err error){
+
+	return dbpt.saveOptionFile((*dbpt).DirPath + "/" + filNam)
+}
+
+func (dbpt *DBObj) saveOptionFile (yamlFilPath string) (err error){
+
+	lotOpt, err := dbpt.LotusDbOption()
+	if err != nil {return err}
+
+	optData, err := yaml.Marshal(lotOpt)
+	if err != nil {return fmt.Errorf("Marshal: %w", err)}
+
+	err = os.WriteFile(yamlFilPath, optData, 0666)
+	if err != nil {return fmt.Errorf("WriteFile: %w", err)}
+
 	return nil
 }
 
 
-func (dbpt *DBObj) FillRan (level int) (keyList, valList []string, err error){
 
-	db := (*dbpt).Db
+func (dbpt *DBObj) FillRan (level int) (keyList, valList []string, err error){
 
 	keyList = make([]string, level)
 	valList = make([]string, level)
@@ -335,94 +547,70 @@ func (dbpt *DBObj) FillRan (level int) (keyList, valList []string, err error){
 		valdat := GenRanData(5, 40)
 		keyList[i] = string(keydat)
 		valList[i] = string(valdat)
-		err = db.Put(keydat, valdat, nil)
-		if err != nil {return keyList, valList, fmt.Errorf("Put[%d] %v", level, err)}
+		err = dbpt.Put(keydat, valdat)
+		if err != nil {return keyList, valList, fmt.Errorf("FillRan[%d]: %w", level, err)}
 	}
 	return keyList, valList, nil
 }
 
 
-func (dbp *DBObj) AddEntry (key, val string) (err error){
+func (dbp *DBObj) AddEntry (key, val string, opts ...WriteOption) (err error){
 
-	db := (*dbp).Db
+	return dbp.put([]byte(key), []byte(val), opts)
+}
 
-	err = db.Put([]byte(key), []byte(val), nil)
-	if err != nil {return fmt.Errorf("Put: %v", err)}
+// InsEntry adds a new entry; it fails with ErrKeyExists if key is already stored.
+// The writes of the table wait until the entry is written.
+func (dbp *DBObj) InsEntry (key, val string, opts ...WriteOption) (err error){
 
-	return nil
-}
+	dbp.wrMu.Lock()
+	defer dbp.wrMu.Unlock()
 
+	res, err := dbp.Exists([]byte(key))
+	if err != nil {return err}
+	if res {return fmt.Errorf("InsEntry %s: %w", key, ErrKeyExists)}
 
-func (dbp *DBObj) UpdEntry (key, val string) (err error){
+	return dbp.putLocked([]byte(key), []byte(val), opts)
+}
 
-    db := (*dbp).Db
-	res, err := db.Exist([]byte(key))
-	if err != nil {return fmt.Errorf("Exist: %v", err)}
-	if !res {return fmt.Errorf("key %s does not exist!", key)}
+// UpdEntry replaces the value of an existing entry; it fails with ErrKeyNotFound if key is not stored.
+// The writes of the table wait until the entry is written.
+func (dbp *DBObj) UpdEntry (key, val string, opts ...WriteOption) (err error){
 
-    err = db.Put([]byte(key), []byte(val), nil)
-    if err != nil {return fmt.Errorf("Put: %v", err)}
+	dbp.wrMu.Lock()
+	defer dbp.wrMu.Unlock()
 
-    return nil
-}
+	res, err := dbp.Exists([]byte(key))
+	if err != nil {return err}
+	if !res {return fmt.Errorf("UpdEntry %s: %w", key, ErrKeyNotFound)}
 
+	return dbp.putLocked([]byte(key), []byte(val), opts)
+}
 
-func (dbp *DBObj) DelEntry (key string) (err error){
 
-	db := (*dbp).Db
-	// todo replace nil with write options
-	err = db.Delete([]byte(key), nil)
-	if err != nil {return fmt.Errorf("Put: %v", err)}
+func (dbp *DBObj) DelEntry (key string, opts ...WriteOption) (err error){
 
-	return nil
+	return dbp.del([]byte(key), opts)
 }
 
 func (dbp *DBObj) GetVal (key string) (valstr string, err error){
 
-	db := (*dbp).Db
-	val, err := db.Get([]byte(key))
-	if err != nil {
-//key not found in database
-//		errStr := err.Error()
-//		fmt.Printf("errStr: %s\n", errStr)
-		return "", fmt.Errorf("Get: %v", err)}
+	val, err := dbp.Get([]byte(key))
+	if err != nil {return "", err}
 
 	return string(val), nil
 }
 
 func (dbp *DBObj) FindKey (key string) (res bool, err error){
 
-	db := (*dbp).Db
-	res, err = db.Exist([]byte(key))
-	if err != nil {return false, fmt.Errorf("Exist: %v", err)}
-
-	return res, nil
+	return dbp.Exists([]byte(key))
 }
 
 
-func (dbp *DBObj) Backup() (err error){
-
-	db := (*dbp).Db
-	err = db.Sync()
-	if err != nil {return fmt.Errorf("could not sync db: %v!", err)}
-	return nil
-}
-
 /*
-func (dbp *DBObj) Backup (tabNam string) (err error){
-
-	return nil
-}
-
-
-func (dbp *DBObj) Load(tabNam string) (err error){
-	var numEntries uint32
-
-}
-
 func (dbp *DBObj) SortHash(){
 
-    db := *dbp
+    db := dbp
 	num := (*db.Entries)
 	hashList := (*db.HashList)[:num]
 	for i:=0; i< len(hashList); i++ {
@@ -466,7 +654,7 @@ func GenRanData (rangeStart, rangeEnd int) (bdat []byte) {
 
 func PrintDb(dbp *DBObj) {
 
-    db := *dbp
+    db := dbp
 //  dbg := db.Dbg
 	opt := db.Opt
 
@@ -475,25 +663,36 @@ func PrintDb(dbp *DBObj) {
     fmt.Printf("TabNam: %s\n",db.TabNam)
 	fmt.Printf("Options:\n")
 	fmt.Printf("  Dir Path:  %s\n", opt.DirPath)
-	fmt.Printf("  MemtableSize: %d\n", opt.MemtableSize)
+	fmt.Printf("  MemtableSize: %s\n", Size(opt.MemtableSize))
 	fmt.Printf("  MemtableNums: %d\n", opt.MemtableNums)
-	fmt.Printf("  BlockCache:   %d\n", opt.BlockCache)
+	fmt.Printf("  BlockCache:   %s\n", Size(opt.BlockCache))
 	fmt.Printf("  Sync:         %t\n", opt.Sync)
-	fmt.Printf("  BytesPerSync: %d\n", opt.BytesPerSync)
+	fmt.Printf("  BytesPerSync: %s\n", Size(opt.BytesPerSync))
 	fmt.Printf("  PartitionNum: %d\n", opt.PartitionNum)
-//	fmt.Printf("  WaitMemSpaceTimeout: %s\n", opt.WaitMemSpaceTimeout)
+	hashNam := db.hashNam
+	if len(hashNam) == 0 {hashNam = "-"}
+	fmt.Printf("  KeyHashFunction: %s\n", hashNam)
+	fmt.Printf("  ValueLogFileSize: %s\n", Size(opt.ValueLogFileSize))
+	idxNam, err := IndexTypeName(opt.IndexType)
+	if err != nil {idxNam = "-"}
+	fmt.Printf("  IndexType:    %s\n", idxNam)
+	fmt.Printf("  CompactBatchCount: %d\n", opt.CompactBatchCount)
+	fmt.Printf("  WaitMemSpaceTimeout: %s\n", opt.WaitMemSpaceTimeout)
+
+	db.optMu.RLock()
+	batch := db.BatchOpt
+	writeOpt := db.Write
+	iterOpt := db.IterOpt
+	db.optMu.RUnlock()
 
-	batch := db.Batch
 	fmt.Printf("  Batch:\n")
 	fmt.Printf("    Sync:       %t\n", batch.Sync)
 	fmt.Printf("    ReadOnly:   %t\n", batch.ReadOnly)
 
-	writeOpt := db.Write
 	fmt.Printf("  Write:\n")
 	fmt.Printf("    Sync:       %t\n", writeOpt.Sync)
 	fmt.Printf("    DisableWal: %t\n", writeOpt.DisableWal)
 
-	iterOpt := db.IterOpt
 	fmt.Printf("  Iterator:\n")
 	prefix := "-"
 	if len(iterOpt.Prefix) >0 {prefix = string(iterOpt.Prefix)} 
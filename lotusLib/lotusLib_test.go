@@ -1,12 +1,16 @@
 package lotusLib
 
 import (
+	"errors"
 	"log"
 //	"fmt"
 	"testing"
 	"os"
+	"reflect"
     "math/rand"
     "time"
+
+	"github.com/lotusdblabs/lotusdb/v2"
 )
 
 func TestDb(t *testing.T) {
@@ -144,7 +148,7 @@ func TestBckupAndLoad(t *testing.T) {
 	keys, vals, err := db.FillRan(5)
 	if err != nil {t.Errorf("error -- FillRan: %v", err)}
 
-	err = db.Backup()
+	_, err = db.Backup("testLotusDb/LotusDbDat.bck")
 	if err != nil {t.Errorf("error -- Backup: %v", err)}
 
 	err = db.Close()
@@ -188,6 +192,121 @@ func TestGet(t *testing.T) {
 
 	if valstr != valList[kidx]  {t.Errorf("values do not agree: %s is not %s!", valstr, valList[kidx])}
 
+	err = db.Close()
+	if err != nil {t.Errorf("error -- could not close Db: %v", err)}
+}
+
+
+func TestKVStore(t *testing.T) {
+
+	err := os.RemoveAll("testLotusDb")
+	if err != nil {t.Errorf("error -- could not remove files: %v", err)}
+
+	db, err := InitDb("testLotusDb", "LotusDbDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+
+	var kv KVStore = db
+
+	err = kv.Batch(func(b KVBatch) error {
+		for _, key := range []string{"b1", "a1", "b2", "c1"} {
+			err := b.Put([]byte(key), []byte("val_" + key))
+			if err != nil {return err}
+		}
+		return nil
+	})
+	if err != nil {t.Errorf("error -- Batch: %v", err)}
+
+	res, err := kv.Exists([]byte("c1"))
+	if err != nil {t.Errorf("error -- Exists: %v", err)}
+	if !res {t.Errorf("error -- key \"c1\" not found!")}
+
+	keyList := []string{}
+	err = kv.Iterate(IteratorOptions{Prefix: "b", Reverse: true}, func(key, val []byte) bool {
+		if string(val) != "val_" + string(key) {t.Errorf("error -- value for %s: %s", key, val)}
+		keyList = append(keyList, string(key))
+		return true
+	})
+	if err != nil {t.Errorf("error -- Iterate: %v", err)}
+	if len(keyList) != 2 || keyList[0] != "b2" || keyList[1] != "b1" {t.Errorf("error -- Iterate keys: %v", keyList)}
+
+	err = kv.Delete([]byte("a1"))
+	if err != nil {t.Errorf("error -- Delete: %v", err)}
+
+	_, err = kv.Get([]byte("a1"))
+	if err == nil {t.Errorf("error -- deleted key \"a1\" found!")}
+
+	err = kv.Close()
+	if err != nil {t.Errorf("error -- could not close Db: %v", err)}
+}
+
+
+func TestErrors(t *testing.T) {
+
+	err := os.RemoveAll("testLotusDb")
+	if err != nil {t.Errorf("error -- could not remove files: %v", err)}
+
+	db, err := InitDb("testLotusDb", "LotusDbDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+
+	_, err = InitDb("testLotusDb", "LotusDbDat", false)
+	if !errors.Is(err, ErrDbLocked) {t.Errorf("error -- second InitDb: %v is not ErrDbLocked", err)}
+
+	_, err = db.GetVal("key1")
+	if !errors.Is(err, ErrKeyNotFound) {t.Errorf("error -- GetVal: %v is not ErrKeyNotFound", err)}
+
+	err = db.UpdEntry("key1", "val1")
+	if !errors.Is(err, ErrKeyNotFound) {t.Errorf("error -- UpdEntry: %v is not ErrKeyNotFound", err)}
+
+	err = db.InsEntry("key1", "val1")
+	if err != nil {t.Errorf("error -- InsEntry: %v", err)}
+
+	err = db.InsEntry("key1", "val1")
+	if !errors.Is(err, ErrKeyExists) {t.Errorf("error -- InsEntry: %v is not ErrKeyExists", err)}
+
+	err = os.WriteFile("testLotusDb/bad.yaml", []byte("MemoryTableSize: big\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	err = db.LoadOption("bad.yaml")
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- LoadOption: %v is not ErrConfig", err)}
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Field != "MemoryTableSize" {t.Errorf("error -- LoadOption: %v does not name MemoryTableSize", err)}
+
+	err = db.Close()
+	if err != nil {t.Errorf("error -- could not close Db: %v", err)}
+
+	_, err = db.GetVal("key1")
+	if !errors.Is(err, ErrClosed) {t.Errorf("error -- GetVal after Close: %v is not ErrClosed", err)}
+}
+
+
+func TestWriteOptions(t *testing.T) {
+
+	db, err := InitDb(t.TempDir(), "LotusDbDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+
+	db.Write.DisableWal = true
+	wopt := db.writeOptions(nil)
+	if wopt.Sync || !wopt.DisableWal {t.Errorf("error -- default write options: %+v", *wopt)}
+
+	wopt = db.writeOptions([]WriteOption{WithSync()})
+	if !wopt.Sync || !wopt.DisableWal {t.Errorf("error -- WithSync write options: %+v", *wopt)}
+	if db.Write.Sync {t.Errorf("error -- WithSync modified the table Write options!")}
+
+	db.Write.DisableWal = false
+	wopt = db.writeOptions([]WriteOption{WithoutWAL()})
+	if wopt.Sync || !wopt.DisableWal {t.Errorf("error -- WithoutWAL write options: %+v", *wopt)}
+
+	err = db.AddEntry("key1", "val1", WithSync())
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	err = db.UpdEntry("key1", "nval1", WithSync(), WithoutWAL())
+	if err != nil {t.Errorf("error -- UpdEntry: %v", err)}
+	valstr, err := db.GetVal("key1")
+	if err != nil || valstr != "nval1" {t.Errorf("error -- GetVal: %s %v", valstr, err)}
+	err = db.DelEntry("key1", WithSync())
+	if err != nil {t.Errorf("error -- DelEntry: %v", err)}
+
+	err = db.Close()
+	if err != nil {t.Errorf("error -- could not close Db: %v", err)}
 }
 
 
@@ -224,3 +343,112 @@ func BenchmarkGet(b *testing.B) {
 }
 
 
+
+func TestOptionRoundTrip(t *testing.T) {
+
+	dirPath := t.TempDir()
+	db, err := InitDb(dirPath, "RoundTrip", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	err = RegisterHashFunc("testHash", func(key []byte) uint64 {return uint64(len(key))})
+	if err != nil {t.Fatalf("error -- RegisterHashFunc: %v", err)}
+	t.Cleanup(func() {unregisterHashFunc("testHash")})
+
+	db.Opt.MemtableSize = 32 << 20
+	db.Opt.MemtableNums = 7
+	db.Opt.BlockCache = 1000000
+	db.Opt.Sync = true
+	db.Opt.BytesPerSync = 4096
+	db.Opt.PartitionNum = 4
+	err = WithHashFunc("testHash")(db)
+	if err != nil {t.Fatalf("error -- WithHashFunc: %v", err)}
+	db.Opt.ValueLogFileSize = 3 << 29
+	db.Opt.IndexType, err = ParseIndexType("hash")
+	if err != nil {t.Fatalf("error -- ParseIndexType: %v", err)}
+	db.Opt.CompactBatchCount = 500
+	db.Opt.WaitMemSpaceTimeout = 250 * time.Millisecond
+	db.BatchOpt.Sync = true
+	db.BatchOpt.ReadOnly = true
+	db.Write.Sync = true
+	db.Write.DisableWal = true
+	db.IterOpt.Prefix = []byte("user:")
+	db.IterOpt.Reverse = true
+
+	err = db.SaveOption("config.yaml")
+	if err != nil {t.Fatalf("error -- SaveOption: %v", err)}
+
+	saved := DBObj{Opt: db.Opt, BatchOpt: db.BatchOpt, Write: db.Write, IterOpt: db.IterOpt}
+
+	// reset to the defaults and load the saved config
+	db.Opt = lotusdb.DefaultOptions
+	db.Opt.DirPath = "other"
+	db.BatchOpt = lotusdb.BatchOptions{}
+	db.Write = lotusdb.WriteOptions{}
+	db.IterOpt = lotusdb.IteratorOptions{}
+
+	err = db.LoadOption("config.yaml")
+	if err != nil {t.Fatalf("error -- LoadOption: %v", err)}
+
+	if db.hashNam != "testHash" || db.Opt.KeyHashFunction == nil {t.Errorf("error -- KeyHashFunction: %q expected testHash", db.hashNam)}
+
+	saved.Opt.KeyHashFunction = nil
+	db.Opt.KeyHashFunction = nil
+	if !reflect.DeepEqual(saved.Opt, db.Opt) {t.Errorf("error -- Options after Load:\n%+v\nexpected\n%+v", db.Opt, saved.Opt)}
+	if saved.BatchOpt != db.BatchOpt {t.Errorf("error -- Batch after Load: %+v expected %+v", db.BatchOpt, saved.BatchOpt)}
+	if saved.Write != db.Write {t.Errorf("error -- Write after Load: %+v expected %+v", db.Write, saved.Write)}
+	if !reflect.DeepEqual(saved.IterOpt, db.IterOpt) {t.Errorf("error -- IterOpt after Load: %+v expected %+v", db.IterOpt, saved.IterOpt)}
+}
+
+func TestSeededHashFunc(t *testing.T) {
+
+	// closures of one func literal share their code pointer
+	seeded := func(seed uint64) func([]byte) uint64 {
+		return func(key []byte) uint64 {return seed + uint64(len(key))}
+	}
+	for i, name := range []string{"seed1", "seed2"} {
+		err := RegisterHashFunc(name, seeded(uint64(i)))
+		if err != nil {t.Fatalf("error -- RegisterHashFunc: %v", err)}
+		t.Cleanup(func() {unregisterHashFunc(name)})
+	}
+
+	dirPath := t.TempDir()
+	db, err := InitDb(dirPath, "Seeded", false, WithHashFunc("seed2"))
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+
+	optObj, err := db.LotusDbOption()
+	if err != nil || optObj.KeyHashFunction != "seed2" {t.Errorf("error -- LotusDbOption KeyHashFunction: %q %v expected seed2", optObj.KeyHashFunction, err)}
+	man, ok, err := ReadManifest(dirPath + "/Seeded")
+	if err != nil || !ok || man.KeyHashFunction != "seed2" {t.Errorf("error -- manifest KeyHashFunction: %q %v expected seed2", man.KeyHashFunction, err)}
+
+	_, err = InitDb(t.TempDir(), "Unknown", false, WithHashFunc("nosuch"))
+	if err == nil {t.Errorf("error -- WithHashFunc of an unregistered function succeeded")}
+}
+
+func TestOptionDefaults(t *testing.T) {
+
+	dirPath := t.TempDir()
+	db, err := InitDb(dirPath, "Defaults", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	err = os.WriteFile(dirPath + "/partial.yaml", []byte("MemTableNumber: 3\nFlushWaitTime: 2s\nIndexType: hash\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	err = db.LoadOption("partial.yaml")
+	if err != nil {t.Fatalf("error -- LoadOption: %v", err)}
+
+	if db.Opt.MemtableNums != 3 {t.Errorf("error -- MemtableNums: %d expected 3", db.Opt.MemtableNums)}
+	if db.Opt.WaitMemSpaceTimeout != 2 * time.Second {t.Errorf("error -- WaitMemSpaceTimeout: %s expected 2s", db.Opt.WaitMemSpaceTimeout)}
+	if db.Opt.IndexType != lotusdb.Hash {t.Errorf("error -- IndexType: %d expected hash", db.Opt.IndexType)}
+	if db.Opt.MemtableSize != lotusdb.DefaultOptions.MemtableSize {t.Errorf("error -- MemtableSize: %d not kept", db.Opt.MemtableSize)}
+	if db.Opt.PartitionNum != lotusdb.DefaultOptions.PartitionNum {t.Errorf("error -- PartitionNum: %d not kept", db.Opt.PartitionNum)}
+	if db.Opt.DirPath != dirPath + "/Defaults" {t.Errorf("error -- DirPath: %s not kept", db.Opt.DirPath)}
+	if db.Opt.KeyHashFunction == nil {t.Errorf("error -- KeyHashFunction not kept")}
+
+	err = os.WriteFile(dirPath + "/bad.yaml", []byte("IndexType: skiplist\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	err = db.LoadOption("bad.yaml")
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- LoadOption with bad IndexType: %v is not ErrConfig", err)}
+}
@@ -0,0 +1,357 @@
+// lotusLibtest
+// conformance test suite for the KVStore backends of lotusLib
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+// Package lotusLibtest validates that a lotusLib.KVStore backend behaves like
+// the lotusdb backend. A backend is validated with one call:
+//
+//	lotusLibtest.RunConformance(t, func(dir string) (lotusLib.KVStore, error) {
+//		return lotusLib.InitDb(dir, "Conformance", false)
+//	})
+package lotusLibtest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// Factory opens the store kept in dir.
+// Each sub test uses a fresh dir. Calling Factory again with the same dir
+// after Close must reopen the same data, unless the suite is Volatile.
+type Factory func(dir string) (kv lotusLib.KVStore, err error)
+
+// EntryStore is the string api of DBObj and MemObj.
+// The entry tests are only run if the store implements it.
+type EntryStore interface {
+	AddEntry(key, val string, opts ...lotusLib.WriteOption) (err error)
+	InsEntry(key, val string, opts ...lotusLib.WriteOption) (err error)
+	UpdEntry(key, val string, opts ...lotusLib.WriteOption) (err error)
+	DelEntry(key string, opts ...lotusLib.WriteOption) (err error)
+	GetVal(key string) (valstr string, err error)
+	FindKey(key string) (res bool, err error)
+}
+
+// Suite is a conformance run.
+type Suite struct {
+	Factory Factory
+
+	// Volatile backends lose their content on Close; the reopen test is skipped.
+	Volatile bool
+}
+
+// RunConformance runs the complete suite against a persistent backend.
+func RunConformance(t *testing.T, factory Factory) {
+	Suite{Factory: factory}.Run(t)
+}
+
+// Run runs every conformance test as a sub test of t.
+func (s Suite) Run(t *testing.T) {
+
+	tests := []struct {
+		name string
+		fn func(t *testing.T, kv lotusLib.KVStore)
+	}{
+		{"PutGet", testPutGet},
+		{"Overwrite", testOverwrite},
+		{"Delete", testDelete},
+		{"Exists", testExists},
+		{"Entries", testEntries},
+		{"EntriesConcurrent", testEntriesConcurrent},
+		{"IterOrder", testIterOrder},
+		{"IterReverse", testIterReverse},
+		{"IterPrefix", testIterPrefix},
+		{"IterStop", testIterStop},
+		{"Batch", testBatch},
+		{"BatchAtomic", testBatchAtomic},
+	}
+
+	for _, tc := range tests {
+		fn := tc.fn
+		t.Run(tc.name, func(t *testing.T) {
+			kv := s.open(t, t.TempDir())
+			defer func() {
+				err := kv.Close()
+				if err != nil {t.Errorf("error -- Close: %v", err)}
+			}()
+			fn(t, kv)
+		})
+	}
+
+	t.Run("Closed", func(t *testing.T) {
+		kv := s.open(t, t.TempDir())
+		fill(t, kv, "key1")
+		err := kv.Close()
+		if err != nil {t.Fatalf("error -- Close: %v", err)}
+		_, err = kv.Get([]byte("key1"))
+		if !errors.Is(err, lotusLib.ErrClosed) {t.Errorf("error -- Get after Close: %v is not ErrClosed", err)}
+	})
+
+	t.Run("Reopen", func(t *testing.T) {
+		if s.Volatile {t.Skip("volatile backend")}
+		s.testReopen(t)
+	})
+}
+
+func (s Suite) open(t *testing.T, dir string) (kv lotusLib.KVStore) {
+
+	t.Helper()
+	kv, err := s.Factory(dir)
+	if err != nil {t.Fatalf("error -- could not open store: %v", err)}
+	if kv == nil {t.Fatalf("error -- factory returned a nil store!")}
+	return kv
+}
+
+func fill(t *testing.T, kv lotusLib.KVStore, keyList ...string) {
+
+	t.Helper()
+	for _, key := range keyList {
+		err := kv.Put([]byte(key), []byte("val_" + key))
+		if err != nil {t.Fatalf("error -- Put %s: %v", key, err)}
+	}
+}
+
+func collect(t *testing.T, kv lotusLib.KVStore, opt lotusLib.IteratorOptions) (keyList []string) {
+
+	t.Helper()
+	keyList = []string{}
+	err := kv.Iterate(opt, func(key, val []byte) bool {
+		if string(val) != "val_" + string(key) {t.Errorf("error -- value for %s: %s", key, val)}
+		keyList = append(keyList, string(key))
+		return true
+	})
+	if err != nil {t.Fatalf("error -- Iterate: %v", err)}
+	return keyList
+}
+
+func checkKeys(t *testing.T, keyList []string, expList ...string) {
+
+	t.Helper()
+	if fmt.Sprint(keyList) != fmt.Sprint(expList) {t.Errorf("error -- keys %v expected %v", keyList, expList)}
+}
+
+func checkVal(t *testing.T, kv lotusLib.KVStore, key, expVal string) {
+
+	t.Helper()
+	val, err := kv.Get([]byte(key))
+	if err != nil {t.Errorf("error -- Get %s: %v", key, err); return}
+	if string(val) != expVal {t.Errorf("error -- value for %s: %s expected %s", key, val, expVal)}
+}
+
+func checkMissing(t *testing.T, kv lotusLib.KVStore, key string) {
+
+	t.Helper()
+	val, err := kv.Get([]byte(key))
+	if err == nil {t.Errorf("error -- Get of missing key %s returned %s", key, val)}
+	if err != nil && !errors.Is(err, lotusLib.ErrKeyNotFound) {t.Errorf("error -- Get of missing key %s: %v is not ErrKeyNotFound", key, err)}
+	res, err := kv.Exists([]byte(key))
+	if err != nil {t.Errorf("error -- Exists %s: %v", key, err)}
+	if res {t.Errorf("error -- missing key %s exists!", key)}
+}
+
+func testPutGet(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "key1", "key2")
+	checkVal(t, kv, "key1", "val_key1")
+	checkVal(t, kv, "key2", "val_key2")
+	checkMissing(t, kv, "key3")
+
+	err := kv.Put([]byte("bin"), []byte{0, 1, 255})
+	if err != nil {t.Fatalf("error -- Put: %v", err)}
+	checkVal(t, kv, "bin", string([]byte{0, 1, 255}))
+}
+
+func testOverwrite(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "key1")
+	err := kv.Put([]byte("key1"), []byte("nval1"))
+	if err != nil {t.Fatalf("error -- Put: %v", err)}
+	checkVal(t, kv, "key1", "nval1")
+}
+
+func testDelete(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "key1", "key2")
+	err := kv.Delete([]byte("key1"))
+	if err != nil {t.Fatalf("error -- Delete: %v", err)}
+	checkMissing(t, kv, "key1")
+	checkVal(t, kv, "key2", "val_key2")
+
+	err = kv.Delete([]byte("key1"))
+	if err != nil {t.Errorf("error -- Delete of missing key: %v", err)}
+}
+
+func testExists(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "key1")
+	res, err := kv.Exists([]byte("key1"))
+	if err != nil {t.Fatalf("error -- Exists: %v", err)}
+	if !res {t.Errorf("error -- key1 does not exist!")}
+	checkMissing(t, kv, "key2")
+}
+
+func testEntries(t *testing.T, kv lotusLib.KVStore) {
+
+	es, ok := kv.(EntryStore)
+	if !ok {t.Skip("store does not implement EntryStore")}
+
+	err := es.UpdEntry("key1", "val1")
+	if !errors.Is(err, lotusLib.ErrKeyNotFound) {t.Errorf("error -- UpdEntry on missing key: %v is not ErrKeyNotFound", err)}
+	checkMissing(t, kv, "key1")
+
+	err = es.InsEntry("key1", "val1")
+	if err != nil {t.Fatalf("error -- InsEntry: %v", err)}
+
+	err = es.InsEntry("key1", "val2")
+	if !errors.Is(err, lotusLib.ErrKeyExists) {t.Errorf("error -- InsEntry on existing key: %v is not ErrKeyExists", err)}
+
+	err = es.AddEntry("key1", "val1")
+	if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+
+	err = es.UpdEntry("key1", "nval1", lotusLib.WithSync())
+	if err != nil {t.Errorf("error -- UpdEntry: %v", err)}
+
+	valstr, err := es.GetVal("key1")
+	if err != nil {t.Errorf("error -- GetVal: %v", err)}
+	if valstr != "nval1" {t.Errorf("error -- GetVal: %s expected nval1", valstr)}
+
+	err = es.DelEntry("key1")
+	if err != nil {t.Errorf("error -- DelEntry: %v", err)}
+
+	res, err := es.FindKey("key1")
+	if err != nil {t.Errorf("error -- FindKey: %v", err)}
+	if res {t.Errorf("error -- deleted key1 found!")}
+
+	_, err = es.GetVal("key1")
+	if err == nil {t.Errorf("error -- GetVal on deleted key succeeded!")}
+}
+
+// testEntriesConcurrent checks that InsEntry and UpdEntry check and write a key atomically.
+func testEntriesConcurrent(t *testing.T, kv lotusLib.KVStore) {
+
+	es, ok := kv.(EntryStore)
+	if !ok {t.Skip("store does not implement EntryStore")}
+
+	const num = 8
+	errs := make(chan error, num)
+	var wg sync.WaitGroup
+	for i := 0; i < num; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- es.InsEntry("key1", fmt.Sprintf("val%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	ins := 0
+	for err := range errs {
+		if err == nil {
+			ins++
+			continue
+		}
+		if !errors.Is(err, lotusLib.ErrKeyExists) {t.Errorf("error -- concurrent InsEntry: %v is not ErrKeyExists", err)}
+	}
+	if ins != 1 {t.Errorf("error -- concurrent InsEntry: %d succeeded expected 1", ins)}
+}
+
+func testIterOrder(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "b2", "a1", "c1", "b1", "ab")
+	keyList := collect(t, kv, lotusLib.IteratorOptions{})
+	checkKeys(t, keyList, "a1", "ab", "b1", "b2", "c1")
+}
+
+func testIterReverse(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "b2", "a1", "c1", "b1")
+	keyList := collect(t, kv, lotusLib.IteratorOptions{Reverse: true})
+	checkKeys(t, keyList, "c1", "b2", "b1", "a1")
+}
+
+func testIterPrefix(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "b2", "a1", "c1", "b1", "bb1")
+	keyList := collect(t, kv, lotusLib.IteratorOptions{Prefix: "b"})
+	checkKeys(t, keyList, "b1", "b2", "bb1")
+
+	keyList = collect(t, kv, lotusLib.IteratorOptions{Prefix: "b", Reverse: true})
+	checkKeys(t, keyList, "bb1", "b2", "b1")
+
+	keyList = collect(t, kv, lotusLib.IteratorOptions{Prefix: "x"})
+	checkKeys(t, keyList)
+}
+
+func testIterStop(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "a", "b", "c", "d")
+	keyList := []string{}
+	err := kv.Iterate(lotusLib.IteratorOptions{}, func(key, val []byte) bool {
+		keyList = append(keyList, string(key))
+		return len(keyList) < 2
+	})
+	if err != nil {t.Fatalf("error -- Iterate: %v", err)}
+	checkKeys(t, keyList, "a", "b")
+}
+
+func testBatch(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "old")
+	err := kv.Batch(func(b lotusLib.KVBatch) error {
+		for _, key := range []string{"key1", "key2"} {
+			err := b.Put([]byte(key), []byte("val_" + key))
+			if err != nil {return err}
+		}
+		return b.Delete([]byte("old"))
+	})
+	if err != nil {t.Fatalf("error -- Batch: %v", err)}
+	checkVal(t, kv, "key1", "val_key1")
+	checkVal(t, kv, "key2", "val_key2")
+	checkMissing(t, kv, "old")
+}
+
+func testBatchAtomic(t *testing.T, kv lotusLib.KVStore) {
+
+	fill(t, kv, "old")
+	errAbort := errors.New("abort")
+	err := kv.Batch(func(b lotusLib.KVBatch) error {
+		err := b.Put([]byte("key1"), []byte("val_key1"))
+		if err != nil {return err}
+		err = b.Delete([]byte("old"))
+		if err != nil {return err}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {t.Errorf("error -- Batch returned %v expected %v", err, errAbort)}
+	checkMissing(t, kv, "key1")
+	checkVal(t, kv, "old", "val_old")
+}
+
+func (s Suite) testReopen(t *testing.T) {
+
+	dir := t.TempDir()
+	kv := s.open(t, dir)
+	fill(t, kv, "key1", "key2", "key3")
+	err := kv.Delete([]byte("key2"))
+	if err != nil {t.Fatalf("error -- Delete: %v", err)}
+	err = kv.Sync()
+	if err != nil {t.Errorf("error -- Sync: %v", err)}
+	err = kv.Close()
+	if err != nil {t.Fatalf("error -- Close: %v", err)}
+
+	kv = s.open(t, dir)
+	defer func() {
+		err := kv.Close()
+		if err != nil {t.Errorf("error -- Close: %v", err)}
+	}()
+	checkVal(t, kv, "key1", "val_key1")
+	checkVal(t, kv, "key3", "val_key3")
+	checkMissing(t, kv, "key2")
+	checkKeys(t, collect(t, kv, lotusLib.IteratorOptions{}), "key1", "key3")
+}
@@ -0,0 +1,307 @@
+// manifest
+// immutable creation options of a table and drift detection
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// ManifestFilNam is the manifest in the table directory DirPath/TabNam.
+// It is written when the table is created and holds the options that cannot
+// change without rewriting the data.
+const ManifestFilNam = "lotusLib.manifest"
+
+// Manifest holds the creation options of a table.
+// The hash function and the number of partitions decide the partition of a key,
+// the index type the format of the index files.
+type Manifest struct {
+	Partitions int `yaml:"Partitions"`
+	IndexType string `yaml:"IndexType"`
+	KeyHashFunction string `yaml:"KeyHashFunction"`
+	Created time.Time `yaml:"Created"`
+}
+
+// Drift is an option that differs from the manifest.
+type Drift struct {
+	Field string
+	Table string
+	Opt string
+}
+
+// DriftError lists the options that differ from the manifest of the table.
+// It matches ErrDrift with errors.Is.
+type DriftError struct {
+	DirPath string
+	Drifts []Drift
+}
+
+func (e *DriftError) Error() string {
+
+	strList := make([]string, len(e.Drifts))
+	for i, d := range e.Drifts {
+		strList[i] = fmt.Sprintf("%s is %s, table has %s", d.Field, d.Opt, d.Table)
+	}
+	return fmt.Sprintf("table %s: %s; open with the table options or allow the migration with WithMigration", e.DirPath, strings.Join(strList, ", "))
+}
+
+func (e *DriftError) Is(target error) bool {
+	return target == ErrDrift
+}
+
+// WithMigration allows InitDb to migrate a table whose creation options differ
+// from the requested options. The entries are copied into a new table with the
+// requested options, which then replaces the old table.
+func WithMigration() InitOption {
+	return func(dbpt *DBObj) error {
+		dbpt.migrate = true
+		return nil
+	}
+}
+
+// manifest returns the manifest of the table options.
+func (dbpt *DBObj) manifest () (man Manifest, err error){
+
+	man.Partitions = dbpt.Opt.PartitionNum
+	man.IndexType, err = IndexTypeName(dbpt.Opt.IndexType)
+	if err != nil {return man, err}
+	man.KeyHashFunction, err = dbpt.hashFuncName()
+	if err != nil {return man, err}
+	return man, nil
+}
+
+// ReadManifest reads the manifest of the table in tabDir; ok is false if there is none.
+func ReadManifest(tabDir string) (man Manifest, ok bool, err error){
+
+	manData, err := os.ReadFile(tabDir + "/" + ManifestFilNam)
+	if os.IsNotExist(err) {return man, false, nil}
+	if err != nil {return man, false, fmt.Errorf("ReadFile: %w", err)}
+
+	err = yaml.Unmarshal(manData, &man)
+	if err != nil {return man, false, fmt.Errorf("manifest %s: %w", tabDir, err)}
+	return man, true, nil
+}
+
+// WriteManifest writes the manifest of the table in tabDir. It records the creation
+// options of a table that has data but neither a manifest nor a config; InitDb refuses
+// to open such a table with ErrNoManifest, since its options cannot be checked.
+func WriteManifest(tabDir string, man Manifest) (err error){
+
+	if man.Created.IsZero() {man.Created = time.Now().UTC()}
+	manData, err := yaml.Marshal(man)
+	if err != nil {return fmt.Errorf("Marshal: %w", err)}
+
+	err = os.WriteFile(tabDir + "/" + ManifestFilNam, manData, 0666)
+	if err != nil {return fmt.Errorf("WriteFile: %w", err)}
+	return nil
+}
+
+// writeManifest writes the manifest of a new table; an existing manifest is kept.
+func (dbpt *DBObj) writeManifest () (err error){
+
+	tabDir := dbpt.Opt.DirPath
+	_, ok, err := ReadManifest(tabDir)
+	if err != nil || ok {return err}
+
+	man, err := dbpt.manifest()
+	if err != nil {return err}
+	return WriteManifest(tabDir, man)
+}
+
+// legacyManifest returns the manifest of a table created without one, from the options of
+// its config file. ok is false for a new table, which has no data yet.
+func legacyManifest(tabDir string) (man Manifest, ok bool, err error){
+
+	entries, err := os.ReadDir(tabDir)
+	if os.IsNotExist(err) {return man, false, nil}
+	if err != nil {return man, false, fmt.Errorf("ReadDir: %w", err)}
+
+	data := false
+	for _, ent := range entries {
+		if ent.Name() != ConfigFilNam && ent.Name() != ManifestFilNam {data = true}
+	}
+	if !data {return man, false, nil}
+
+	optObj, err := newDb("", "", false).LotusDbOption()
+	if err != nil {return man, false, err}
+	err = readOption(tabDir + "/" + ConfigFilNam, &optObj)
+	if errors.Is(err, fs.ErrNotExist) {return man, false, fmt.Errorf("table %s: %w; write its creation options with WriteManifest", tabDir, ErrNoManifest)}
+	if err != nil {return man, false, err}
+
+	man = Manifest{Partitions: optObj.PartitionNum, IndexType: optObj.IndexType, KeyHashFunction: optObj.KeyHashFunction}
+	err = WriteManifest(tabDir, man)
+	if err != nil {return man, false, err}
+	return man, true, nil
+}
+
+// recoverSwap finishes the replacement of the table directory by a migration or a restore
+// that was interrupted. Both move the table to tabDir.old once the new table in
+// tabDir.migrate or tabDir.restore is complete, and then move the new table in place.
+func recoverSwap(tabDir string) (err error){
+
+	oldDir := tabDir + ".old"
+	_, err = os.Stat(oldDir)
+	if os.IsNotExist(err) {return nil}
+	if err != nil {return fmt.Errorf("recover %s: %w", tabDir, err)}
+
+	_, err = os.Stat(tabDir)
+	if err != nil && !os.IsNotExist(err) {return fmt.Errorf("recover %s: %w", tabDir, err)}
+	if os.IsNotExist(err) {
+		// without a new table the old table is moved back
+		src := oldDir
+		for _, newDir := range []string{tabDir + ".migrate", tabDir + ".restore"} {
+			_, err = os.Stat(newDir)
+			if err == nil {
+				src = newDir
+				break
+			}
+		}
+		err = os.Rename(src, tabDir)
+		if err != nil {return fmt.Errorf("recover %s: %w", tabDir, err)}
+		if src == oldDir {return nil}
+	}
+
+	err = os.RemoveAll(oldDir)
+	if err != nil {return fmt.Errorf("recover %s: %w", tabDir, err)}
+	return nil
+}
+
+// checkManifest compares the table options with the manifest of the table.
+// If they differ, it returns a DriftError or migrates the table, if allowed.
+// An interrupted migration or restore is finished first.
+func (dbpt *DBObj) checkManifest () (err error){
+
+	tabDir := dbpt.Opt.DirPath
+	err = recoverSwap(tabDir)
+	if err != nil {return err}
+
+	tabMan, ok, err := ReadManifest(tabDir)
+	if err != nil {return err}
+	if !ok {
+		tabMan, ok, err = legacyManifest(tabDir)
+		if err != nil || !ok {return err}
+	}
+
+	man, err := dbpt.manifest()
+	if err != nil {return err}
+
+	var drifts []Drift
+	if man.Partitions != tabMan.Partitions {
+		drifts = append(drifts, Drift{Field: "Partitions", Table: strconv.Itoa(tabMan.Partitions), Opt: strconv.Itoa(man.Partitions)})
+	}
+	if man.IndexType != tabMan.IndexType {
+		drifts = append(drifts, Drift{Field: "IndexType", Table: tabMan.IndexType, Opt: man.IndexType})
+	}
+	if man.KeyHashFunction != tabMan.KeyHashFunction {
+		drifts = append(drifts, Drift{Field: "KeyHashFunction", Table: tabMan.KeyHashFunction, Opt: man.KeyHashFunction})
+	}
+	if len(drifts) == 0 {return nil}
+
+	driftErr := &DriftError{DirPath: tabDir, Drifts: drifts}
+	if !dbpt.migrate {return driftErr}
+
+	if dbpt.Dbg {log.Printf("migrating %s\n", driftErr)}
+	return dbpt.migrateTable(tabMan)
+}
+
+// migrateTable copies the entries of the table, opened with the options of tabMan,
+// into a new table with the table options, and replaces the old table with it.
+func (dbpt *DBObj) migrateTable (tabMan Manifest) (err error){
+
+	tabDir := dbpt.Opt.DirPath
+	newDir := tabDir + ".migrate"
+	oldDir := tabDir + ".old"
+
+	oldOpt := dbpt.Opt
+	oldOpt.PartitionNum = tabMan.Partitions
+	oldOpt.IndexType, err = ParseIndexType(tabMan.IndexType)
+	if err != nil {return err}
+	oldOpt.KeyHashFunction, err = HashFunc(tabMan.KeyHashFunction)
+	if err != nil {return err}
+
+	newOpt := dbpt.Opt
+	newOpt.DirPath = newDir
+
+	err = os.RemoveAll(newDir)
+	if err != nil {return fmt.Errorf("migrate: %w", err)}
+
+	err = copyTable(oldOpt, newOpt)
+	if err == nil {
+		// the manifest marks the new table as complete; see recoverSwap
+		var man Manifest
+		man, err = dbpt.manifest()
+		if err == nil {err = WriteManifest(newDir, man)}
+	}
+	if err != nil {
+		os.RemoveAll(newDir)
+		return fmt.Errorf("migrate %s: %w", tabDir, err)
+	}
+
+	err = os.Rename(tabDir, oldDir)
+	if err != nil {return fmt.Errorf("migrate: %w", err)}
+	err = os.Rename(newDir, tabDir)
+	if err != nil {
+		os.Rename(oldDir, tabDir)
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	err = os.RemoveAll(oldDir)
+	if err != nil {return fmt.Errorf("migrate: %w", err)}
+	return nil
+}
+
+// copyTable copies all entries of the lotusdb table of srcOpt into a new table with dstOpt.
+func copyTable(srcOpt, dstOpt lotusdb.Options) (err error){
+
+	src, err := lotusdb.Open(srcOpt)
+	if err != nil {return dbErr("lotusdb.Open " + srcOpt.DirPath, err)}
+	defer src.Close()
+
+	_, err = copyEntries(src, dstOpt)
+	return err
+}
+
+// copyEntries copies all entries of src into a new lotusdb table with dstOpt
+// and returns the number of entries.
+func copyEntries(src *lotusdb.DB, dstOpt lotusdb.Options) (num int, err error){
+
+	dst, err := lotusdb.Open(dstOpt)
+	if err != nil {return 0, dbErr("lotusdb.Open " + dstOpt.DirPath, err)}
+
+	iter, err := src.NewIterator(lotusdb.IteratorOptions{})
+	if err != nil {
+		dst.Close()
+		return 0, dbErr("NewIterator", err)
+	}
+	defer iter.Close()
+
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		err = dst.Put(iter.Key(), iter.Value(), &lotusdb.WriteOptions{})
+		if err != nil {
+			dst.Close()
+			return num, dbErr("Put", err)
+		}
+		num++
+	}
+
+	err = dst.Sync()
+	if err != nil {
+		dst.Close()
+		return num, dbErr("Sync", err)
+	}
+	return num, dst.Close()
+}
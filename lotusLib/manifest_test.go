@@ -0,0 +1,114 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "ManDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	err = db.AddEntry("key1", "val1")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	err = db.AddEntry("key2", "val2")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	parts := db.Opt.PartitionNum
+	db.Close()
+
+	man, ok, err := ReadManifest(dir + "/ManDat")
+	if err != nil || !ok {t.Fatalf("error -- ReadManifest: %t %v", ok, err)}
+	if man.Partitions != parts || man.IndexType != "btree" || man.KeyHashFunction != "xxhash" {t.Errorf("error -- manifest: %+v", man)}
+
+	// fast-ingest has 8 partitions
+	_, err = InitDb(dir, "ManDat", false, WithPreset("fast-ingest"))
+	var driftErr *DriftError
+	if !errors.Is(err, ErrDrift) || !errors.As(err, &driftErr) {t.Fatalf("error -- drift not detected: %v", err)}
+	if len(driftErr.Drifts) != 1 || driftErr.Drifts[0].Field != "Partitions" {t.Errorf("error -- drifts: %+v", driftErr.Drifts)}
+
+	db, err = InitDb(dir, "ManDat", false, WithPreset("fast-ingest"), WithMigration())
+	if err != nil {t.Fatalf("error -- InitDb with migration: %v", err)}
+	valstr, err := db.GetVal("key2")
+	if err != nil || valstr != "val2" {t.Errorf("error -- GetVal after migration: %s %v", valstr, err)}
+	db.Close()
+
+	man, _, err = ReadManifest(dir + "/ManDat")
+	if err != nil || man.Partitions != 8 {t.Errorf("error -- manifest after migration: %+v %v", man, err)}
+
+	// the persisted config has the migrated options
+	db, err = InitDb(dir, "ManDat", false)
+	if err != nil {t.Fatalf("error -- InitDb after migration: %v", err)}
+	defer db.Close()
+	if db.Opt.PartitionNum != 8 {t.Errorf("error -- Partitions %d expected 8", db.Opt.PartitionNum)}
+}
+
+func TestLegacyManifest(t *testing.T) {
+
+	dir := t.TempDir()
+	tabDir := dir + "/OldDat"
+	db, err := InitDb(dir, "OldDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	err = db.AddEntry("key1", "val1")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	db.Close()
+
+	// a table without a manifest gets the creation options of its config, not the requested ones
+	err = os.Remove(tabDir + "/" + ManifestFilNam)
+	if err != nil {t.Fatalf("error -- Remove: %v", err)}
+	_, err = InitDb(dir, "OldDat", false, WithPreset("fast-ingest"))
+	if !errors.Is(err, ErrDrift) {t.Errorf("error -- legacy table opened with other partitions: %v", err)}
+	man, ok, err := ReadManifest(tabDir)
+	if err != nil || !ok || man.Partitions == 8 {t.Errorf("error -- legacy manifest: %+v %t %v", man, ok, err)}
+
+	// without a config the options are unknown
+	for _, filNam := range []string{ManifestFilNam, ConfigFilNam} {
+		err = os.Remove(tabDir + "/" + filNam)
+		if err != nil {t.Fatalf("error -- Remove: %v", err)}
+	}
+	_, err = InitDb(dir, "OldDat", false)
+	if !errors.Is(err, ErrNoManifest) {t.Fatalf("error -- table without manifest and config: %v is not ErrNoManifest", err)}
+
+	err = WriteManifest(tabDir, man)
+	if err != nil {t.Fatalf("error -- WriteManifest: %v", err)}
+	db, err = InitDb(dir, "OldDat", false)
+	if err != nil {t.Fatalf("error -- InitDb after WriteManifest: %v", err)}
+	defer db.Close()
+	valstr, err := db.GetVal("key1")
+	if err != nil || valstr != "val1" {t.Errorf("error -- GetVal: %s %v", valstr, err)}
+}
+
+func TestRecoverMigration(t *testing.T) {
+
+	dir := t.TempDir()
+	tabDir := dir + "/MigDat"
+	db, err := InitDb(dir, "MigDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	err = db.AddEntry("key1", "val1")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	db.Close()
+
+	// crash after the old table was moved: the complete new table is moved in place
+	err = os.Rename(tabDir, tabDir + ".migrate")
+	if err == nil {err = os.Mkdir(tabDir + ".old", 0755)}
+	if err != nil {t.Fatalf("error -- simulate migration: %v", err)}
+	db, err = InitDb(dir, "MigDat", false)
+	if err != nil {t.Fatalf("error -- InitDb after interrupted migration: %v", err)}
+	valstr, err := db.GetVal("key1")
+	if err != nil || valstr != "val1" {t.Errorf("error -- GetVal after recovery: %s %v", valstr, err)}
+	db.Close()
+	for _, leftDir := range []string{tabDir + ".migrate", tabDir + ".old"} {
+		_, err = os.Stat(leftDir)
+		if !os.IsNotExist(err) {t.Errorf("error -- %s left after recovery: %v", leftDir, err)}
+	}
+
+	// crash before the new table was complete: the old table is moved back
+	err = os.Rename(tabDir, tabDir + ".old")
+	if err != nil {t.Fatalf("error -- simulate migration: %v", err)}
+	db, err = InitDb(dir, "MigDat", false)
+	if err != nil {t.Fatalf("error -- InitDb after interrupted migration: %v", err)}
+	defer db.Close()
+	valstr, err = db.GetVal("key1")
+	if err != nil || valstr != "val1" {t.Errorf("error -- GetVal after moving the old table back: %s %v", valstr, err)}
+}
@@ -0,0 +1,270 @@
+// memDb
+// in-memory ordered backend of KVStore
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemObj is a pure Go in-memory backend with the same API as DBObj.
+// The keys are kept in a sorted slice, so that iteration is ordered.
+// Nothing is written to disk; the content is lost on Close.
+type MemObj struct {
+	TabNam string
+	Dbg bool
+	BatchOpt BatchOpt
+	IterOpt IteratorOptions
+	mu sync.RWMutex
+	keys []string
+	vals map[string][]byte
+	closed bool
+}
+
+type memOp struct {
+	key string
+	val []byte
+	del bool
+}
+
+type memBatch struct {
+	readOnly bool
+	ops []memOp
+}
+
+var _ KVStore = (*MemObj)(nil)
+
+func InitMemDb(tabNam string, dbg bool) (dbp *MemObj, err error){
+
+	db := MemObj {
+		TabNam: tabNam,
+		Dbg: dbg,
+		vals: make(map[string][]byte),
+	}
+	return &db, nil
+}
+
+func (dbp *MemObj) Close () (err error){
+
+	dbp.mu.Lock()
+	defer dbp.mu.Unlock()
+	if dbp.closed {return fmt.Errorf("Close: %w", ErrClosed)}
+	dbp.closed = true
+	dbp.keys = nil
+	dbp.vals = nil
+	return nil
+}
+
+// find returns the position of key in the sorted key slice and whether it is present.
+func (dbp *MemObj) find (key string) (idx int, res bool){
+
+	idx = sort.SearchStrings(dbp.keys, key)
+	res = idx < len(dbp.keys) && dbp.keys[idx] == key
+	return idx, res
+}
+
+// set stores or removes a key; the caller holds the write lock.
+func (dbp *MemObj) set (key string, val []byte, del bool) {
+
+	idx, res := dbp.find(key)
+	if del {
+		if !res {return}
+		dbp.keys = append(dbp.keys[:idx], dbp.keys[idx+1:]...)
+		delete(dbp.vals, key)
+		return
+	}
+	if !res {
+		dbp.keys = append(dbp.keys, "")
+		copy(dbp.keys[idx+1:], dbp.keys[idx:])
+		dbp.keys[idx] = key
+	}
+	dbp.vals[key] = append([]byte(nil), val...)
+}
+
+func (dbp *MemObj) Get (key []byte) (val []byte, err error){
+
+	if len(key) == 0 {return nil, fmt.Errorf("Get: %w", ErrKeyEmpty)}
+	dbp.mu.RLock()
+	defer dbp.mu.RUnlock()
+	if dbp.closed {return nil, fmt.Errorf("Get: %w", ErrClosed)}
+
+	v, ok := dbp.vals[string(key)]
+	if !ok {return nil, fmt.Errorf("Get: %w", ErrKeyNotFound)}
+
+	return append([]byte(nil), v...), nil
+}
+
+func (dbp *MemObj) Put (key, val []byte) (err error){
+
+	if len(key) == 0 {return fmt.Errorf("Put: %w", ErrKeyEmpty)}
+	dbp.mu.Lock()
+	defer dbp.mu.Unlock()
+	if dbp.closed {return fmt.Errorf("Put: %w", ErrClosed)}
+
+	dbp.set(string(key), val, false)
+	return nil
+}
+
+func (dbp *MemObj) Delete (key []byte) (err error){
+
+	if len(key) == 0 {return fmt.Errorf("Delete: %w", ErrKeyEmpty)}
+	dbp.mu.Lock()
+	defer dbp.mu.Unlock()
+	if dbp.closed {return fmt.Errorf("Delete: %w", ErrClosed)}
+
+	dbp.set(string(key), nil, true)
+	return nil
+}
+
+func (dbp *MemObj) Exists (key []byte) (res bool, err error){
+
+	if len(key) == 0 {return false, fmt.Errorf("Exist: %w", ErrKeyEmpty)}
+	dbp.mu.RLock()
+	defer dbp.mu.RUnlock()
+	if dbp.closed {return false, fmt.Errorf("Exist: %w", ErrClosed)}
+
+	_, res = dbp.vals[string(key)]
+	return res, nil
+}
+
+// Iterate walks a copy of the selected key range, so fn may write to the store.
+func (dbp *MemObj) Iterate (opt IteratorOptions, fn func(key, val []byte) bool) (err error){
+
+	dbp.mu.RLock()
+	if dbp.closed {
+		dbp.mu.RUnlock()
+		return fmt.Errorf("NewIterator: %w", ErrClosed)
+	}
+	start := sort.SearchStrings(dbp.keys, opt.Prefix)
+	end := start
+	for end < len(dbp.keys) && strings.HasPrefix(dbp.keys[end], opt.Prefix) {end++}
+
+	keyList := make([]string, end-start)
+	valList := make([][]byte, end-start)
+	copy(keyList, dbp.keys[start:end])
+	for i, key := range keyList {
+		valList[i] = append([]byte(nil), dbp.vals[key]...)
+	}
+	dbp.mu.RUnlock()
+
+	for i := 0; i < len(keyList); i++ {
+		idx := i
+		if opt.Reverse {idx = len(keyList) - 1 - i}
+		if !fn([]byte(keyList[idx]), valList[idx]) {break}
+	}
+	return nil
+}
+
+func (dbp *MemObj) Batch (fn func(b KVBatch) error) (err error){
+
+	batch := &memBatch{readOnly: dbp.BatchOpt.ReadOnly}
+	err = fn(batch)
+	if err != nil {return err}
+
+	dbp.mu.Lock()
+	defer dbp.mu.Unlock()
+	if dbp.closed {return fmt.Errorf("Commit: %w", ErrClosed)}
+	for _, op := range batch.ops {
+		dbp.set(op.key, op.val, op.del)
+	}
+	return nil
+}
+
+func (b *memBatch) Put (key, val []byte) (err error){
+
+	if len(key) == 0 {return ErrKeyEmpty}
+	if b.readOnly {return ErrReadOnly}
+	b.ops = append(b.ops, memOp{key: string(key), val: append([]byte(nil), val...)})
+	return nil
+}
+
+func (b *memBatch) Delete (key []byte) (err error){
+
+	if len(key) == 0 {return ErrKeyEmpty}
+	if b.readOnly {return ErrReadOnly}
+	b.ops = append(b.ops, memOp{key: string(key), del: true})
+	return nil
+}
+
+// Sync is a no-op; there is no stable storage behind MemObj.
+func (dbp *MemObj) Sync () (err error){
+
+	dbp.mu.RLock()
+	defer dbp.mu.RUnlock()
+	if dbp.closed {return fmt.Errorf("Sync: %w", ErrClosed)}
+	return nil
+}
+
+func (dbp *MemObj) FillRan (level int) (keyList, valList []string, err error){
+
+	keyList = make([]string, level)
+	valList = make([]string, level)
+	for i:=0; i<level; i++ {
+		keydat := GenRanData(5, 25)
+		valdat := GenRanData(5, 40)
+		keyList[i] = string(keydat)
+		valList[i] = string(valdat)
+		err = dbp.Put(keydat, valdat)
+		if err != nil {return keyList, valList, fmt.Errorf("FillRan[%d]: %w", level, err)}
+	}
+	return keyList, valList, nil
+}
+
+// The write options are accepted for compatibility with DBObj; MemObj has no storage to sync.
+func (dbp *MemObj) AddEntry (key, val string, opts ...WriteOption) (err error){
+
+	return dbp.Put([]byte(key), []byte(val))
+}
+
+// InsEntry adds a new entry; it fails with ErrKeyExists if key is already stored.
+func (dbp *MemObj) InsEntry (key, val string, opts ...WriteOption) (err error){
+
+	if len(key) == 0 {return fmt.Errorf("InsEntry: %w", ErrKeyEmpty)}
+	dbp.mu.Lock()
+	defer dbp.mu.Unlock()
+	if dbp.closed {return fmt.Errorf("InsEntry: %w", ErrClosed)}
+
+	_, res := dbp.find(key)
+	if res {return fmt.Errorf("InsEntry %s: %w", key, ErrKeyExists)}
+	dbp.set(key, []byte(val), false)
+	return nil
+}
+
+// UpdEntry replaces the value of an existing entry; it fails with ErrKeyNotFound if key is not stored.
+func (dbp *MemObj) UpdEntry (key, val string, opts ...WriteOption) (err error){
+
+	if len(key) == 0 {return fmt.Errorf("UpdEntry: %w", ErrKeyEmpty)}
+	dbp.mu.Lock()
+	defer dbp.mu.Unlock()
+	if dbp.closed {return fmt.Errorf("UpdEntry: %w", ErrClosed)}
+
+	_, res := dbp.find(key)
+	if !res {return fmt.Errorf("UpdEntry %s: %w", key, ErrKeyNotFound)}
+	dbp.set(key, []byte(val), false)
+	return nil
+}
+
+func (dbp *MemObj) DelEntry (key string, opts ...WriteOption) (err error){
+
+	return dbp.Delete([]byte(key))
+}
+
+func (dbp *MemObj) GetVal (key string) (valstr string, err error){
+
+	val, err := dbp.Get([]byte(key))
+	if err != nil {return "", err}
+
+	return string(val), nil
+}
+
+func (dbp *MemObj) FindKey (key string) (res bool, err error){
+
+	return dbp.Exists([]byte(key))
+}
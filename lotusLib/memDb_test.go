@@ -0,0 +1,103 @@
+package lotusLib
+
+import (
+	"testing"
+)
+
+func TestMemEntry(t *testing.T) {
+
+	db, err := InitMemDb("MemDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise MemDb: %v", err)}
+
+	err = db.AddEntry("key1", "val1")
+	if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+
+	res, err := db.FindKey("key1")
+	if err != nil {t.Errorf("error -- FindKey: %v", err)}
+	if !res {t.Errorf("error -- key \"key1\" not found!")}
+
+	res, err = db.FindKey("key2")
+	if err != nil {t.Errorf("error -- FindKey: %v", err)}
+	if res {t.Errorf("error -- non-existent key \"key2\" found!")}
+
+	valstr, err := db.GetVal("key2")
+	if err == nil {t.Errorf("error -- GetVal for key \"key2\": %s", valstr)}
+
+	err = db.UpdEntry("key2", "val2")
+	if err == nil {t.Errorf("error -- UpdEntry for missing key \"key2\" succeeded!")}
+
+	err = db.UpdEntry("key1", "nval1")
+	if err != nil {t.Errorf("error -- UpdEntry for \"key1\": %v", err)}
+
+	valstr, err = db.GetVal("key1")
+	if err != nil {t.Errorf("error -- GetVal for \"key1\": %v", err)}
+	if valstr != "nval1" {t.Errorf("error - values do not match: %s %s", valstr, "nval1")}
+
+	err = db.DelEntry("key1")
+	if err != nil {t.Errorf("error -- DelEntry: %v", err)}
+
+	res, err = db.FindKey("key1")
+	if err != nil {t.Errorf("error -- FindKey: %v", err)}
+	if res {t.Errorf("error -- FindKey: \"key1\" found! Should not exist!")}
+
+	err = db.Close()
+	if err != nil {t.Errorf("error -- could not close MemDb: %v", err)}
+
+	_, err = db.GetVal("key1")
+	if err == nil {t.Errorf("error -- GetVal after Close succeeded!")}
+}
+
+func TestMemIterate(t *testing.T) {
+
+	db, err := InitMemDb("MemDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise MemDb: %v", err)}
+
+	for _, key := range []string{"b2", "a1", "c1", "b1", "b3"} {
+		err = db.AddEntry(key, "val_" + key)
+		if err != nil {t.Errorf("error -- AddEntry: %v", err)}
+	}
+
+	keyList := []string{}
+	err = db.Iterate(IteratorOptions{}, func(key, val []byte) bool {
+		keyList = append(keyList, string(key))
+		return true
+	})
+	if err != nil {t.Errorf("error -- Iterate: %v", err)}
+	if len(keyList) != 5 || keyList[0] != "a1" || keyList[4] != "c1" {t.Errorf("error -- Iterate keys: %v", keyList)}
+
+	keyList = []string{}
+	err = db.Iterate(IteratorOptions{Prefix: "b", Reverse: true}, func(key, val []byte) bool {
+		keyList = append(keyList, string(key))
+		return len(keyList) < 2
+	})
+	if err != nil {t.Errorf("error -- Iterate: %v", err)}
+	if len(keyList) != 2 || keyList[0] != "b3" || keyList[1] != "b2" {t.Errorf("error -- Iterate reverse keys: %v", keyList)}
+
+	db.BatchOpt.ReadOnly = true
+	err = db.Batch(func(b KVBatch) error {
+		return b.Put([]byte("d1"), []byte("val_d1"))
+	})
+	if err == nil {t.Errorf("error -- Put in read only batch succeeded!")}
+
+	err = db.Close()
+	if err != nil {t.Errorf("error -- could not close MemDb: %v", err)}
+}
+
+func BenchmarkMemGet(b *testing.B) {
+
+	db, err := InitMemDb("MemDat", false)
+	if err != nil {b.Fatalf("error -- could not initialise MemDb: %v", err)}
+
+	numEntries := 100
+	keyList, valList, err := db.FillRan(numEntries)
+	if err != nil {b.Fatalf("error -- FillRan: %v", err)}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		kidx := n % numEntries
+		valstr, err := db.GetVal(keyList[kidx])
+		if err != nil {b.Fatalf("GetVal err invalid keyStr!")}
+		if valstr != valList[kidx] {b.Fatalf("values do not agree[%d]: %s is not %s!", n, valstr, valList[kidx])}
+	}
+}
@@ -0,0 +1,219 @@
+// options
+// conversion between lotusdb options and the yaml config
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// indexTypes maps the yaml names of the index types.
+var indexTypes = map[string]lotusdb.IndexType{
+	"btree": lotusdb.BTree,
+	"hash": lotusdb.Hash,
+}
+
+var hashMu sync.RWMutex
+
+// hashFuncs holds the key hash functions that can be selected by name in the config.
+var hashFuncs = map[string]func([]byte) uint64{
+	"xxhash": lotusdb.DefaultOptions.KeyHashFunction,
+	"fnv64a": fnv64a,
+}
+
+func fnv64a(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	return h.Sum64()
+}
+
+// ParseIndexType returns the lotusdb index type for a yaml name (btree or hash).
+func ParseIndexType(name string) (it lotusdb.IndexType, err error){
+
+	it, ok := indexTypes[strings.ToLower(name)]
+	if !ok {return 0, &ConfigError{Field: "IndexType", Reason: fmt.Sprintf("unknown index type %q (btree, hash)", name)}}
+	return it, nil
+}
+
+// IndexTypeName returns the yaml name of a lotusdb index type.
+func IndexTypeName(it lotusdb.IndexType) (name string, err error){
+
+	for name, t := range indexTypes {
+		if t == it {return name, nil}
+	}
+	return "", &ConfigError{Field: "IndexType", Reason: fmt.Sprintf("unknown index type %d", it)}
+}
+
+// RegisterHashFunc makes a key hash function selectable by name in the config.
+// The hash function decides the partition of a key, so a table has to be
+// reopened with the function it was created with.
+func RegisterHashFunc(name string, fn func([]byte) uint64) (err error){
+
+	if len(name) == 0 || fn == nil {return fmt.Errorf("RegisterHashFunc: empty name or nil function")}
+	hashMu.Lock()
+	defer hashMu.Unlock()
+	if _, ok := hashFuncs[name]; ok {return fmt.Errorf("RegisterHashFunc %s: already registered", name)}
+	hashFuncs[name] = fn
+	return nil
+}
+
+// unregisterHashFunc removes the hash function name; tests use it to undo RegisterHashFunc.
+func unregisterHashFunc(name string) {
+
+	hashMu.Lock()
+	defer hashMu.Unlock()
+	delete(hashFuncs, name)
+}
+
+// HashFunc returns the hash function registered as name.
+func HashFunc(name string) (fn func([]byte) uint64, err error){
+
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+	fn, ok := hashFuncs[name]
+	if !ok {return nil, &ConfigError{Field: "KeyHashFunction", Reason: fmt.Sprintf("hash function %q is not registered (%s)", name, strings.Join(hashFuncNames(), ", "))}}
+	return fn, nil
+}
+
+// WithHashFunc opens the table with the key hash function registered as name.
+func WithHashFunc(name string) InitOption {
+	return func(dbpt *DBObj) error {
+		fn, err := HashFunc(name)
+		if err != nil {return err}
+		dbpt.Opt.KeyHashFunction = fn
+		dbpt.hashNam = name
+		return nil
+	}
+}
+
+// hashFuncName returns the registered name of the key hash function of the table.
+func (dbpt *DBObj) hashFuncName () (name string, err error){
+
+	if dbpt.Opt.KeyHashFunction == nil {return "", &ConfigError{Field: "KeyHashFunction", Reason: "no hash function"}}
+	if len(dbpt.hashNam) == 0 {return "", &ConfigError{Field: "KeyHashFunction", Reason: "hash function has no registered name; use WithHashFunc"}}
+	return dbpt.hashNam, nil
+}
+
+// hashFuncNames returns the sorted names of the registered hash functions; the caller holds hashMu.
+func hashFuncNames() (names []string){
+
+	for name := range hashFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LotusDbOption returns the yaml representation of the table options.
+func (dbpt *DBObj) LotusDbOption () (lotOpt LotusDbOption, err error){
+
+	opt := (*dbpt).Opt
+
+	lotOpt.DirPath = (*dbpt).DirPath
+	lotOpt.TabNam = (*dbpt).TabNam
+
+	lotOpt.MemtableSize = Size(opt.MemtableSize)
+	lotOpt.MemtableNums = opt.MemtableNums
+
+	lotOpt.BlockCache = Size(opt.BlockCache)
+	lotOpt.Sync = opt.Sync
+
+	lotOpt.BytesPerSync = Size(opt.BytesPerSync)
+	lotOpt.PartitionNum = opt.PartitionNum
+
+	lotOpt.KeyHashFunction, err = dbpt.hashFuncName()
+	if err != nil {return lotOpt, err}
+
+	lotOpt.ValueLogFileSize = Size(opt.ValueLogFileSize)
+
+	lotOpt.IndexType, err = IndexTypeName(opt.IndexType)
+	if err != nil {return lotOpt, err}
+
+	lotOpt.CompactBatchCount = opt.CompactBatchCount
+	lotOpt.WaitMemSpaceTimeout = opt.WaitMemSpaceTimeout
+
+	dbpt.optMu.RLock()
+	defer dbpt.optMu.RUnlock()
+	lotOpt.Batch.Sync = (*dbpt).BatchOpt.Sync
+	lotOpt.Batch.ReadOnly = (*dbpt).BatchOpt.ReadOnly
+
+	lotOpt.Write.Sync = (*dbpt).Write.Sync
+	lotOpt.Write.DisableWal = (*dbpt).Write.DisableWal
+
+	lotOpt.IterOpt.Prefix = string((*dbpt).IterOpt.Prefix)
+	lotOpt.IterOpt.Reverse = (*dbpt).IterOpt.Reverse
+
+	return lotOpt, nil
+}
+
+// Validate checks the options without opening the table. It returns ConfigErrors with
+// every field that cannot be converted or is invalid, including a missing DirPath, or nil.
+func (lotOpt *LotusDbOption) Validate () (err error){
+
+	var errs ConfigErrors
+	if len(lotOpt.DirPath) == 0 {errs.add("DirPath", "the database directory path cannot be empty")}
+
+	opt, _, _, _, err := lotOpt.Options()
+	if err != nil {errs.merge(err)}
+
+	cand := DBObj{DirPath: lotOpt.DirPath, TabNam: lotOpt.TabNam, Opt: opt, hashNam: lotOpt.KeyHashFunction}
+	err = cand.ValidateOpts()
+	if err != nil {errs.merge(err)}
+	return errs.err()
+}
+
+// Options converts the yaml representation into the table options.
+// It returns ConfigErrors with every field that cannot be converted.
+func (lotOpt *LotusDbOption) Options () (opt lotusdb.Options, batch lotusdb.BatchOptions, write lotusdb.WriteOptions, iterOpt lotusdb.IteratorOptions, err error){
+
+	var errs ConfigErrors
+
+	opt.DirPath = lotOpt.DirPath + "/" + lotOpt.TabNam
+
+	opt.MemtableSize, err = lotOpt.MemtableSize.Uint32("MemoryTableSize")
+	errs.addErr("MemoryTableSize", err)
+	opt.MemtableNums = lotOpt.MemtableNums
+
+	opt.BlockCache, err = lotOpt.BlockCache.Uint32("BlockCache")
+	errs.addErr("BlockCache", err)
+
+	opt.Sync = lotOpt.Sync
+
+	opt.BytesPerSync, err = lotOpt.BytesPerSync.Uint32("SyncSize")
+	errs.addErr("SyncSize", err)
+
+	opt.PartitionNum = lotOpt.PartitionNum
+
+	opt.KeyHashFunction, err = HashFunc(lotOpt.KeyHashFunction)
+	errs.addErr("KeyHashFunction", err)
+
+	opt.ValueLogFileSize, err = lotOpt.ValueLogFileSize.Int64("VLogSize")
+	errs.addErr("VLogSize", err)
+
+	opt.IndexType, err = ParseIndexType(lotOpt.IndexType)
+	errs.addErr("IndexType", err)
+
+	opt.CompactBatchCount = lotOpt.CompactBatchCount
+	opt.WaitMemSpaceTimeout = lotOpt.WaitMemSpaceTimeout
+
+	batch.Sync = lotOpt.Batch.Sync
+	batch.ReadOnly = lotOpt.Batch.ReadOnly
+
+	write.Sync = lotOpt.Write.Sync
+	write.DisableWal = lotOpt.Write.DisableWal
+
+	if len(lotOpt.IterOpt.Prefix) > 0 {iterOpt.Prefix = []byte(lotOpt.IterOpt.Prefix)}
+	iterOpt.Reverse = lotOpt.IterOpt.Reverse
+
+	return opt, batch, write, iterOpt, errs.err()
+}
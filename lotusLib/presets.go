@@ -0,0 +1,122 @@
+// presets
+// named combinations of table, write and batch options
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preset is a named combination of table, write and batch options.
+// A preset sets only the options it tunes; the other options keep their value.
+type Preset struct {
+	Name string
+	Desc string
+	// Keys are the yaml keys set by the preset.
+	Keys []string
+	apply func(lotOpt *LotusDbOption)
+}
+
+var presets = map[string]Preset{
+	"durable": {
+		Name: "durable",
+		Desc: "sync every write and batch to disk; slowest writes, no data loss after a crash",
+		Keys: []string{"MemoryTableSize", "MemTableNumber", "Sync", "SyncSize", "WriteOpt.Sync", "WriteOpt.DisableWAL", "Batch.Sync"},
+		apply: func(lotOpt *LotusDbOption) {
+			lotOpt.MemtableSize = 64 << 20
+			lotOpt.MemtableNums = 15
+			lotOpt.Sync = true
+			lotOpt.BytesPerSync = 0
+			lotOpt.Write.Sync = true
+			lotOpt.Write.DisableWal = false
+			lotOpt.Batch.Sync = true
+		},
+	},
+	"fast-ingest": {
+		Name: "fast-ingest",
+		Desc: "large memtables and 8 partitions for bulk loads; a machine crash loses the writes of the last 8MiB",
+		Keys: []string{"MemoryTableSize", "MemTableNumber", "Partitions", "Sync", "SyncSize", "WriteOpt.Sync", "WriteOpt.DisableWAL", "Batch.Sync"},
+		apply: func(lotOpt *LotusDbOption) {
+			lotOpt.MemtableSize = 128 << 20
+			lotOpt.MemtableNums = 10
+			lotOpt.PartitionNum = 8
+			lotOpt.Sync = false
+			lotOpt.BytesPerSync = 8 << 20
+			lotOpt.Write.Sync = false
+			lotOpt.Write.DisableWal = false
+			lotOpt.Batch.Sync = false
+		},
+	},
+	"low-memory": {
+		Name: "low-memory",
+		Desc: "two 8MiB memtables, one partition and no block cache; for small tables and containers",
+		Keys: []string{"MemoryTableSize", "MemTableNumber", "BlockCache", "Partitions", "Sync", "SyncSize"},
+		apply: func(lotOpt *LotusDbOption) {
+			lotOpt.MemtableSize = 8 << 20
+			lotOpt.MemtableNums = 2
+			lotOpt.BlockCache = 0
+			lotOpt.PartitionNum = 1
+			lotOpt.Sync = false
+			lotOpt.BytesPerSync = 1 << 20
+		},
+	},
+}
+
+// Presets returns the presets sorted by name.
+func Presets() (list []Preset) {
+
+	for _, p := range presets {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {return list[i].Name < list[j].Name})
+	return list
+}
+
+func presetNames() string {
+
+	var names []string
+	for _, p := range Presets() {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ApplyPreset sets the options of the preset name.
+func (lotOpt *LotusDbOption) ApplyPreset (name string) (err error){
+
+	p, ok := presets[name]
+	if !ok {return &ConfigError{Field: "Preset", Reason: fmt.Sprintf("unknown preset %q (%s)", name, presetNames())}}
+	p.apply(lotOpt)
+	lotOpt.Preset = name
+	return nil
+}
+
+// ApplyPreset sets the options of the preset name on the table.
+// The table has to be reopened for the lotusdb options to take effect.
+func (dbpt *DBObj) ApplyPreset (name string) (err error){
+
+	optObj, err := dbpt.LotusDbOption()
+	if err != nil {return err}
+
+	err = optObj.ApplyPreset(name)
+	if err != nil {return err}
+
+	return dbpt.setOption(&optObj)
+}
+
+// InitOption is an option of InitDb.
+type InitOption func(dbpt *DBObj) error
+
+// WithPreset opens the table with the preset name.
+// The preset overrides the config kept in the table directory.
+func WithPreset(name string) InitOption {
+	return func(dbpt *DBObj) error {
+		return dbpt.ApplyPreset(name)
+	}
+}
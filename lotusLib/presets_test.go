@@ -0,0 +1,62 @@
+package lotusLib
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+
+	if len(Presets()) != 3 {t.Errorf("error -- %d presets expected 3", len(Presets()))}
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "PresetDat", false, WithPreset("low-memory"))
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	if db.Opt.MemtableSize != 8 << 20 || db.Opt.MemtableNums != 2 || db.Opt.PartitionNum != 1 {
+		t.Errorf("error -- low-memory options: %d %d %d", db.Opt.MemtableSize, db.Opt.MemtableNums, db.Opt.PartitionNum)
+	}
+	db.Close()
+
+	_, err = InitDb(dir, "PresetDat2", false, WithPreset("tiny"))
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- unknown preset: %v is not ErrConfig", err)}
+
+	// keys of the file override the preset
+	cfgPath := dir + "/cfg.yaml"
+	cfg := "TableName: CfgDat\nPreset: durable\nMemoryTableSize: 16MiB\n"
+	err = os.WriteFile(cfgPath, []byte(cfg), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	db, err = OpenFromConfig(cfgPath, false)
+	if err != nil {t.Fatalf("error -- OpenFromConfig: %v", err)}
+	defer db.Close()
+	if db.Opt.MemtableSize != 16 << 20 {t.Errorf("error -- MemoryTableSize %d expected 16MiB", db.Opt.MemtableSize)}
+	if !db.Opt.Sync || !db.Write.Sync || !db.BatchOpt.Sync {t.Errorf("error -- durable options not set")}
+
+	cl := ConfigLoader{FilPath: cfgPath, LookupEnv: func(string) (string, bool) {return "", false}}
+	_, rep, err := cl.Load()
+	if err != nil {t.Fatalf("error -- Load: %v", err)}
+	for key, want := range map[string]Layer{"Preset": LayerFile, "Batch.Sync": LayerPreset, "MemoryTableSize": LayerFile, "BlockCache": LayerDefault} {
+		l, _ := rep.Layer(key)
+		if l != want {t.Errorf("error -- %s set by %s expected %s", key, l, want)}
+	}
+
+	// the preset of a higher layer replaces the preset of the file
+	cl.LookupEnv = func(key string) (string, bool) {return "low-memory", key == "LOTUS_PRESET"}
+	optObj, rep, err := cl.Load()
+	if err != nil {t.Fatalf("error -- Load with LOTUS_PRESET: %v", err)}
+	if optObj.Preset != "low-memory" || optObj.PartitionNum != 1 || optObj.MemtableSize != 16 << 20 || optObj.Batch.Sync {t.Errorf("error -- LOTUS_PRESET options: %+v", optObj)}
+	for key, want := range map[string]Layer{"Preset": LayerEnv, "Partitions": LayerPreset, "MemoryTableSize": LayerFile} {
+		l, _ := rep.Layer(key)
+		if l != want {t.Errorf("error -- %s set by %s expected %s with LOTUS_PRESET", key, l, want)}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cl.BindFlags(fs)
+	err = fs.Parse([]string{"-Preset=nosuch"})
+	if err != nil {t.Fatalf("error -- flag Parse: %v", err)}
+	_, _, err = cl.Load()
+	if !errors.Is(err, ErrConfig) || !strings.Contains(err.Error(), "nosuch") {t.Errorf("error -- -Preset of an unknown preset: %v", err)}
+}
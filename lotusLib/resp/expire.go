@@ -0,0 +1,174 @@
+// expire
+// expiry times of the redis keys, kept in a sidecar table of the table
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+// The expiry time of key is the entry key of the sidecar table ttlKind of the table
+// (see lotusLib.Catalog.OpenSidecar) with the deadline in unix milliseconds and the
+// checksum of the value it was set for, so it survives a restart of the server and
+// the other users of the table do not see it. A key that was written since with
+// another value, e.g. by the http server or the lotus tool, does not match the
+// checksum; it keeps its value and the expiry time is removed.
+// The table is committed before the expiry times, so an interrupted command at
+// worst leaves a key without its expiry time.
+
+package resp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// ttlKind is the sidecar table of the expiry times.
+const ttlKind = "ttl"
+
+// tabState is the state of a table served by the server.
+type tabState struct {
+	db *lotusLib.DBObj
+	// ttl holds the expiry times of the keys of db.
+	ttl *lotusLib.DBObj
+
+	// mu serializes the writes, so that SET NX|XX, INCR and DEL see a key that does
+	// not change until they write it, and the removal of expired keys. Reads do not take it.
+	mu sync.Mutex
+}
+
+// valSum is the checksum of the value an expiry time was set for.
+func valSum(val string) string {
+
+	sum := sha256.Sum256([]byte(val))
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseTTL returns the deadline and the checksum of the expiry time rec of key.
+func parseTTL(key, rec string) (dl time.Time, sum string, err error) {
+
+	msStr, sum, ok := strings.Cut(rec, " ")
+	ms, err := strconv.ParseInt(msStr, 10, 64)
+	if !ok || err != nil {return dl, "", fmt.Errorf("invalid expiry time of %q: %q", key, rec)}
+	return time.UnixMilli(ms), sum, nil
+}
+
+// deadline returns the expiry time of key with the value val; ok is false if key has
+// none or the expiry time was set for another value.
+func (ts *tabState) deadline(key, val string) (dl time.Time, ok bool, err error) {
+
+	rec, err := ts.ttl.Get([]byte(key))
+	if errors.Is(err, lotusLib.ErrKeyNotFound) {return dl, false, nil}
+	if err != nil {return dl, false, err}
+	dl, sum, err := parseTTL(key, string(rec))
+	if err != nil {return dl, false, err}
+	if sum != valSum(val) {return time.Time{}, false, nil}
+	return dl, true, nil
+}
+
+// expire removes key if its expiry time has passed at now.
+func (ts *tabState) expire(key string, now time.Time) (err error) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	valdat, err := ts.db.Get([]byte(key))
+	if errors.Is(err, lotusLib.ErrKeyNotFound) {return nil}
+	if err != nil {return err}
+	dl, ok, err := ts.deadline(key, string(valdat))
+	if err != nil || !ok || now.Before(dl) {return err}
+
+	b := ts.newBatch()
+	b.del(key)
+	b.setDeadline(key, "", time.Time{})
+	return b.commit()
+}
+
+// purge removes all keys whose expiry time has passed at now, and the expiry times
+// of keys that were deleted or written with another value.
+func (ts *tabState) purge(now time.Time) (err error) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	it, err := ts.ttl.NewIterator(lotusLib.WithPrefix(""), lotusLib.WithReverse(false))
+	if err != nil {return err}
+
+	sums := make(map[string]string)
+	for it.Next() {
+		dl, sum, perr := parseTTL(it.Key(), it.Value())
+		if perr == nil && now.Before(dl) {continue}
+		sums[it.Key()] = sum
+	}
+	err = it.Close()
+	if err != nil {return err}
+	if len(sums) == 0 {return nil}
+
+	b := ts.newBatch()
+	for key, sum := range sums {
+		valdat, err := ts.db.Get([]byte(key))
+		if err != nil && !errors.Is(err, lotusLib.ErrKeyNotFound) {
+			b.rollback()
+			return err
+		}
+		if err == nil && sum == valSum(string(valdat)) {b.del(key)}
+		b.setDeadline(key, "", time.Time{})
+	}
+	return b.commit()
+}
+
+// batch collects the writes of a command to the table and to its expiry times.
+// The first error is kept and returned by commit.
+type batch struct {
+	b *lotusLib.BatchObj
+	ttl *lotusLib.BatchObj
+	err error
+}
+
+func (ts *tabState) newBatch() *batch {
+	return &batch{b: ts.db.NewBatch(), ttl: ts.ttl.NewBatch()}
+}
+
+func (b *batch) put(key, val string) {
+	if b.err == nil {b.err = b.b.Put([]byte(key), []byte(val))}
+}
+
+func (b *batch) del(key string) {
+	if b.err == nil {b.err = b.b.Delete([]byte(key))}
+}
+
+// setDeadline writes the expiry time of key with the value val; a zero deadline removes it.
+func (b *batch) setDeadline(key, val string, dl time.Time) {
+
+	if b.err != nil {return}
+	if dl.IsZero() {
+		b.err = b.ttl.Delete([]byte(key))
+		return
+	}
+	b.err = b.ttl.Put([]byte(key), []byte(strconv.FormatInt(dl.UnixMilli(), 10) + " " + valSum(val)))
+}
+
+// commit commits the table and then the expiry times.
+func (b *batch) commit() (err error) {
+
+	if b.err != nil {
+		b.rollback()
+		return b.err
+	}
+	err = b.b.Commit()
+	if err != nil {
+		b.ttl.Rollback()
+		return err
+	}
+	return b.ttl.Commit()
+}
+
+func (b *batch) rollback() {
+	b.b.Rollback()
+	b.ttl.Rollback()
+}
@@ -0,0 +1,172 @@
+// proto
+// reading and writing the redis serialization protocol (resp2)
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxArgs is the maximum number of arguments of a command.
+	maxArgs = 1024 * 1024
+	// maxBulk is the maximum length of an argument.
+	maxBulk = 64 << 20
+)
+
+var errProto = errors.New("Protocol error")
+
+// readLine returns the next line without the line end.
+func readLine(r *bufio.Reader) (line string, err error) {
+
+	data, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {return "", fmt.Errorf("%w: line too long", errProto)}
+	if err != nil {return "", err}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// readCommand reads a command, either as an array of bulk strings or as an inline
+// command with the arguments separated by spaces. An empty command returns no args.
+func readCommand(r *bufio.Reader) (args []string, err error) {
+
+	line, err := readLine(r)
+	if err != nil {return nil, err}
+	if len(line) == 0 || line[0] != '*' {return strings.Fields(line), nil}
+
+	num, err := strconv.Atoi(line[1:])
+	if err != nil || num > maxArgs {return nil, fmt.Errorf("%w: invalid multibulk length", errProto)}
+
+	args = make([]string, 0, max(num, 0))
+	for i := 0; i < num; i++ {
+		line, err = readLine(r)
+		if err != nil {return nil, err}
+		if len(line) == 0 || line[0] != '$' {return nil, fmt.Errorf("%w: expected '$', got %q", errProto, line)}
+		blen, err := strconv.Atoi(line[1:])
+		if err != nil || blen < 0 || blen > maxBulk {return nil, fmt.Errorf("%w: invalid bulk length", errProto)}
+
+		data := make([]byte, blen + 2)
+		_, err = io.ReadFull(r, data)
+		if err != nil {return nil, err}
+		if data[blen] != '\r' || data[blen+1] != '\n' {return nil, fmt.Errorf("%w: bulk string without CRLF", errProto)}
+		args = append(args, string(data[:blen]))
+	}
+	return args, nil
+}
+
+// writer writes resp2 replies.
+type writer struct {
+	*bufio.Writer
+}
+
+func (w writer) simple(str string) {
+	w.WriteString("+" + str + "\r\n")
+}
+
+// error writes an error reply; msg starts with the error code, e.g. "ERR".
+func (w writer) error(msg string) {
+	w.WriteString("-" + strings.NewReplacer("\r", " ", "\n", " ").Replace(msg) + "\r\n")
+}
+
+func (w writer) int(num int64) {
+	w.WriteString(":" + strconv.FormatInt(num, 10) + "\r\n")
+}
+
+func (w writer) bulk(str string) {
+	w.WriteString("$" + strconv.Itoa(len(str)) + "\r\n" + str + "\r\n")
+}
+
+func (w writer) null() {
+	w.WriteString("$-1\r\n")
+}
+
+func (w writer) array(num int) {
+	w.WriteString("*" + strconv.Itoa(num) + "\r\n")
+}
+
+func (w writer) bulks(list []string) {
+
+	w.array(len(list))
+	for _, str := range list {
+		w.bulk(str)
+	}
+}
+
+// match reports whether str matches the glob pattern of the redis KEYS and SCAN
+// commands: * any string, ? any byte, [abc], [^abc] and [a-z] classes, \ escapes.
+func match(pattern, str string) bool {
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {pattern = pattern[1:]}
+			if len(pattern) == 0 {return true}
+			for i := 0; i <= len(str); i++ {
+				if match(pattern, str[i:]) {return true}
+			}
+			return false
+
+		case '?':
+			if len(str) == 0 {return false}
+			pattern, str = pattern[1:], str[1:]
+
+		case '[':
+			if len(str) == 0 {return false}
+			rest, ok := matchClass(pattern, str[0])
+			if !ok {return false}
+			pattern, str = rest, str[1:]
+
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {pattern = pattern[1:]}
+			if len(str) == 0 || str[0] != pattern[0] {return false}
+			pattern, str = pattern[1:], str[1:]
+		}
+	}
+	return len(str) == 0
+}
+
+// matchClass matches c with the class "[...]" at the start of pattern and returns the rest of the pattern.
+func matchClass(pattern string, c byte) (rest string, ok bool) {
+
+	i := 1
+	not := false
+	if i < len(pattern) && pattern[i] == '^' {
+		not = true
+		i++
+	}
+
+	found := false
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			if pattern[i+1] == c {found = true}
+			i += 2
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {lo, hi = hi, lo}
+			if c >= lo && c <= hi {found = true}
+			i += 3
+		default:
+			if pattern[i] == c {found = true}
+			i++
+		}
+	}
+	if i < len(pattern) {i++}
+	return pattern[i:], found != not
+}
+
+// literalPrefix returns the part of pattern before the first wildcard.
+func literalPrefix(pattern string) string {
+
+	idx := strings.IndexAny(pattern, "*?[\\")
+	if idx < 0 {return pattern}
+	return pattern[:idx]
+}
@@ -0,0 +1,83 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+
+	tests := []struct {
+		in string
+		args string
+	}{
+		{"*2\r\n$3\r\nGET\r\n$5\r\nuser1\r\n", "[GET user1]"},
+		{"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\na\r\nb\r\n", "[SET k a\r\nb]"},
+		{"*1\r\n$0\r\n\r\n", "[]"},
+		{"PING hello\r\n", "[PING hello]"},
+		{"\r\n", "[]"},
+	}
+
+	for _, tc := range tests {
+		args, err := readCommand(bufio.NewReader(strings.NewReader(tc.in)))
+		if err != nil {t.Errorf("error -- readCommand %q: %v", tc.in, err); continue}
+		if fmt.Sprint(args) != tc.args {t.Errorf("error -- readCommand %q: %q expected %s", tc.in, args, tc.args)}
+	}
+
+	for _, in := range []string{"*x\r\n", "*1\r\n:1\r\n", "*1\r\n$-2\r\n", "*1\r\n$2\r\nabc\r\n"} {
+		_, err := readCommand(bufio.NewReader(strings.NewReader(in)))
+		if !errors.Is(err, errProto) {t.Errorf("error -- readCommand %q: %v expected a protocol error", in, err)}
+	}
+}
+
+func TestWriter(t *testing.T) {
+
+	var buf bytes.Buffer
+	w := writer{bufio.NewWriter(&buf)}
+	w.simple("OK")
+	w.error("ERR bad\r\nline")
+	w.int(-3)
+	w.bulks([]string{"a", ""})
+	w.null()
+	w.Flush()
+
+	if buf.String() != "+OK\r\n-ERR bad  line\r\n:-3\r\n*2\r\n$1\r\na\r\n$0\r\n\r\n$-1\r\n" {t.Errorf("error -- writer: %q", buf.String())}
+}
+
+func TestMatch(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		str string
+		res bool
+	}{
+		{"*", "", true},
+		{"*", "user:1", true},
+		{"user:*", "user:1", true},
+		{"user:*", "item:1", false},
+		{"*:1", "user:1", true},
+		{"u?er", "user", true},
+		{"u?er", "uer", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"key[0-9]", "key5", true},
+		{"key[0-9]", "keyx", false},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"a*b*c", "a/x/b/y/c", true},
+		{"user", "user", true},
+		{"user", "users", false},
+	}
+
+	for _, tc := range tests {
+		if match(tc.pattern, tc.str) != tc.res {t.Errorf("error -- match(%q, %q) expected %v", tc.pattern, tc.str, tc.res)}
+	}
+
+	if literalPrefix("user:*") != "user:" || literalPrefix("a\\*") != "a" || literalPrefix("abc") != "abc" {t.Errorf("error -- literalPrefix")}
+}
@@ -0,0 +1,657 @@
+// server
+// redis protocol front end for the tables of a catalog
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+// Server speaks a subset of resp2, so that redis clients can use the tables:
+//
+//	PING [message]
+//	GET key
+//	SET key value [NX|XX] [EX seconds]
+//	DEL key [key ...]
+//	EXISTS key [key ...]
+//	SCAN cursor [MATCH pattern] [COUNT count]
+//	KEYS pattern
+//	MGET key [key ...]
+//	MSET key value [key value ...]
+//	INCR key
+//	INFO [section]
+//	DBSIZE
+//	SELECT table
+//	QUIT
+//
+// SELECT selects a table of the catalog by name; an integer n that is not a table
+// name selects the nth table in name order, so SELECT 0 is the first table.
+// Expiry times set with EX are kept in a sidecar table of the table (see expire.go), so
+// they survive a restart. An expired key is removed when a command reads it, by DBSIZE
+// and when the server first uses the table. SCAN cursors are random ids kept by the
+// server for all connections, so that a pooled client can continue a SCAN on another
+// connection; the least recently used are dropped after maxCursors.
+
+package resp
+
+import (
+	"bufio"
+	"container/list"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("resp: server closed")
+
+// maxCursors is the number of SCAN cursors that are kept.
+const maxCursors = 1024
+
+// Server serves the tables of a catalog with the redis protocol.
+type Server struct {
+	Cat *lotusLib.Catalog
+
+	// Default is the table of a new connection; if it is empty the first table is selected.
+	Default string
+
+	now func() time.Time
+
+	// tabMu guards tabs, the state of the tables used by the server.
+	tabMu sync.Mutex
+	tabs map[string]*tabState
+
+	// curMu guards the SCAN cursors; curList holds them with the most recently used first.
+	curMu sync.Mutex
+	cursors map[uint64]*list.Element
+	curList *list.List
+
+	connMu sync.Mutex
+	closed bool
+	listeners map[net.Listener]bool
+	conns map[net.Conn]bool
+	wg sync.WaitGroup
+}
+
+// New returns a server for the tables of cat with the table defTab selected by default.
+func New(cat *lotusLib.Catalog, defTab string) (srv *Server) {
+
+	srv = &Server{
+		Cat: cat,
+		Default: defTab,
+		now: time.Now,
+		tabs: make(map[string]*tabState),
+		cursors: make(map[uint64]*list.Element),
+		curList: list.New(),
+		listeners: make(map[net.Listener]bool),
+		conns: make(map[net.Conn]bool),
+	}
+	return srv
+}
+
+// Serve accepts connections on ln until Close. It always returns an error; after Close ErrServerClosed.
+// It returns the error of Default at once if Default cannot be opened.
+func (srv *Server) Serve(ln net.Listener) (err error) {
+
+	if len(srv.Default) > 0 {
+		_, err = srv.table(srv.Default)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+	}
+
+	srv.connMu.Lock()
+	if srv.closed {
+		srv.connMu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	srv.listeners[ln] = true
+	srv.connMu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			srv.connMu.Lock()
+			closed := srv.closed
+			delete(srv.listeners, ln)
+			srv.connMu.Unlock()
+			if closed {return ErrServerClosed}
+			return err
+		}
+
+		srv.connMu.Lock()
+		if srv.closed {
+			srv.connMu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		srv.conns[conn] = true
+		srv.wg.Add(1)
+		srv.connMu.Unlock()
+
+		go srv.handle(conn)
+	}
+}
+
+// Close stops the listeners, closes all connections and waits for their commands to end.
+func (srv *Server) Close() (err error) {
+
+	srv.connMu.Lock()
+	srv.closed = true
+	for ln := range srv.listeners {
+		lerr := ln.Close()
+		if lerr != nil && err == nil {err = lerr}
+	}
+	for conn := range srv.conns {
+		conn.Close()
+	}
+	srv.connMu.Unlock()
+
+	srv.wg.Wait()
+	return err
+}
+
+func (srv *Server) clients() int {
+
+	srv.connMu.Lock()
+	defer srv.connMu.Unlock()
+	return len(srv.conns)
+}
+
+// session is the state of a connection.
+type session struct {
+	srv *Server
+	w writer
+	tab *tabState
+	quit bool
+}
+
+// scanCursor is the position of a SCAN of the table tab: the last key examined.
+type scanCursor struct {
+	id uint64
+	tab *tabState
+	key string
+}
+
+// newCursor returns a new random cursor id for the position key of a SCAN of tab.
+// The least recently used cursor is dropped if there are more than maxCursors.
+func (srv *Server) newCursor(tab *tabState, key string) (id uint64, err error) {
+
+	srv.curMu.Lock()
+	defer srv.curMu.Unlock()
+
+	for id == 0 || srv.cursors[id] != nil {
+		var buf [8]byte
+		_, err = rand.Read(buf[:])
+		if err != nil {return 0, err}
+		id = binary.BigEndian.Uint64(buf[:])
+	}
+	srv.cursors[id] = srv.curList.PushFront(&scanCursor{id: id, tab: tab, key: key})
+
+	if srv.curList.Len() > maxCursors {
+		el := srv.curList.Back()
+		srv.curList.Remove(el)
+		delete(srv.cursors, el.Value.(*scanCursor).id)
+	}
+	return id, nil
+}
+
+// cursor returns the position of the cursor id of a SCAN of tab; ok is false if the
+// cursor is unknown or belongs to another table.
+func (srv *Server) cursor(id uint64, tab *tabState) (key string, ok bool) {
+
+	srv.curMu.Lock()
+	defer srv.curMu.Unlock()
+
+	el := srv.cursors[id]
+	if el == nil {return "", false}
+	cur := el.Value.(*scanCursor)
+	if cur.tab != tab {return "", false}
+	srv.curList.MoveToFront(el)
+	return cur.key, true
+}
+
+func (srv *Server) handle(conn net.Conn) {
+
+	defer func() {
+		conn.Close()
+		srv.connMu.Lock()
+		delete(srv.conns, conn)
+		srv.connMu.Unlock()
+		srv.wg.Done()
+	}()
+
+	r := bufio.NewReaderSize(conn, 64*1024)
+	s := &session{srv: srv, w: writer{bufio.NewWriter(conn)}}
+
+	tab := srv.Default
+	if len(tab) == 0 {tab = "0"}
+	s.tab, _ = srv.table(tab)
+
+	for !s.quit {
+		args, err := readCommand(r)
+		if errors.Is(err, errProto) {
+			s.w.error("ERR " + err.Error())
+			s.w.Flush()
+			return
+		}
+		if err != nil {return}
+		if len(args) == 0 {continue}
+
+		s.exec(args)
+		if r.Buffered() == 0 || s.quit {
+			err = s.w.Flush()
+			if err != nil {return}
+		}
+	}
+}
+
+// table returns the table name, or the nth table of the catalog for an integer that is not a table name.
+func (srv *Server) table(name string) (ts *tabState, err error) {
+
+	db, err := srv.Cat.Open(name)
+	if errors.Is(err, lotusLib.ErrTableNotFound) {
+		idx, nerr := strconv.Atoi(name)
+		list := srv.Cat.List()
+		if nerr != nil || idx < 0 || idx >= len(list) {return nil, err}
+		db, err = srv.Cat.Open(list[idx].Name)
+	}
+	if err != nil {return nil, err}
+	return srv.state(db)
+}
+
+// state returns the state of the table db; the expired keys are removed when the server first uses it.
+func (srv *Server) state(db *lotusLib.DBObj) (ts *tabState, err error) {
+
+	srv.tabMu.Lock()
+	ts = srv.tabs[db.TabNam]
+	if ts != nil && ts.db == db {
+		srv.tabMu.Unlock()
+		return ts, nil
+	}
+	ttl, err := srv.Cat.OpenSidecar(db.TabNam, ttlKind)
+	if err != nil {
+		srv.tabMu.Unlock()
+		return nil, err
+	}
+	ts = &tabState{db: db, ttl: ttl}
+	srv.tabs[db.TabNam] = ts
+	srv.tabMu.Unlock()
+
+	err = ts.purge(srv.now())
+	if err != nil {return nil, err}
+	return ts, nil
+}
+
+// cmdDef is a command; minArgs and maxArgs do not count the command name, maxArgs -1 is no limit.
+type cmdDef struct {
+	minArgs int
+	maxArgs int
+	// table is set if the command needs a selected table.
+	table bool
+	run func(s *session, args []string)
+}
+
+var cmds map[string]cmdDef
+
+func init() {
+	cmds = map[string]cmdDef{
+		"PING": {0, 1, false, (*session).ping},
+		"QUIT": {0, 0, false, (*session).quitCmd},
+		"SELECT": {1, 1, false, (*session).selectCmd},
+		"INFO": {0, 1, false, (*session).info},
+		"GET": {1, 1, true, (*session).get},
+		"SET": {2, 5, true, (*session).set},
+		"DEL": {1, -1, true, (*session).del},
+		"EXISTS": {1, -1, true, (*session).exists},
+		"SCAN": {1, 5, true, (*session).scan},
+		"KEYS": {1, 1, true, (*session).keys},
+		"MGET": {1, -1, true, (*session).mget},
+		"MSET": {2, -1, true, (*session).mset},
+		"INCR": {1, 1, true, (*session).incr},
+		"DBSIZE": {0, 0, true, (*session).dbsize},
+	}
+}
+
+func (s *session) exec(args []string) {
+
+	name := strings.ToUpper(args[0])
+	cmd, ok := cmds[name]
+	if !ok {
+		s.w.error(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return
+	}
+	args = args[1:]
+	if len(args) < cmd.minArgs || (cmd.maxArgs >= 0 && len(args) > cmd.maxArgs) {
+		s.w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return
+	}
+	if cmd.table && s.tab == nil {
+		s.w.error("ERR no table selected, use SELECT")
+		return
+	}
+	cmd.run(s, args)
+}
+
+func (s *session) dbErr(err error) {
+	s.w.error("ERR " + err.Error())
+}
+
+// getVal returns the value of key; ok is false if key does not exist or has expired.
+// expired is set if the expiry time of key has passed; the key is still in the table.
+func (s *session) getVal(key string) (val string, ok, expired bool, err error) {
+
+	valdat, err := s.tab.db.Get([]byte(key))
+	if errors.Is(err, lotusLib.ErrKeyNotFound) {return "", false, false, nil}
+	if err != nil {return "", false, false, err}
+
+	dl, hasDl, err := s.tab.deadline(key, string(valdat))
+	if err != nil {return "", false, false, err}
+	if hasDl && !s.srv.now().Before(dl) {return "", false, true, nil}
+	return string(valdat), true, false, nil
+}
+
+// read returns the value of key for the commands that do not lock the table; an expired key is removed.
+func (s *session) read(key string) (val string, ok bool, err error) {
+
+	val, ok, expired, err := s.getVal(key)
+	if err != nil || !expired {return val, ok, err}
+	return "", false, s.tab.expire(key, s.srv.now())
+}
+
+func (s *session) ping(args []string) {
+
+	if len(args) == 1 {
+		s.w.bulk(args[0])
+		return
+	}
+	s.w.simple("PONG")
+}
+
+func (s *session) quitCmd(args []string) {
+
+	s.w.simple("OK")
+	s.quit = true
+}
+
+func (s *session) selectCmd(args []string) {
+
+	ts, err := s.srv.table(args[0])
+	if err != nil {s.dbErr(err); return}
+	s.tab = ts
+	s.w.simple("OK")
+}
+
+func (s *session) info(args []string) {
+
+	var sb strings.Builder
+	sb.WriteString("# Server\r\nredis_mode:standalone\r\n")
+	if s.tab != nil {fmt.Fprintf(&sb, "lotus_table:%s\r\nlotus_dir:%s\r\n", s.tab.db.TabNam, s.tab.db.DirPath)}
+	fmt.Fprintf(&sb, "\r\n# Clients\r\nconnected_clients:%d\r\n", s.srv.clients())
+	sb.WriteString("\r\n# Keyspace\r\n")
+	for idx, info := range s.srv.Cat.List() {
+		fmt.Fprintf(&sb, "db%d:table=%s\r\n", idx, info.Name)
+	}
+	s.w.bulk(sb.String())
+}
+
+func (s *session) get(args []string) {
+
+	val, ok, err := s.read(args[0])
+	if err != nil {s.dbErr(err); return}
+	if !ok {s.w.null(); return}
+	s.w.bulk(val)
+}
+
+func (s *session) set(args []string) {
+
+	key, val := args[0], args[1]
+	nx, xx := false, false
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX":
+			i++
+			if i == len(args) {s.w.error("ERR syntax error"); return}
+			secs, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || secs <= 0 || secs > 1e9 {s.w.error("ERR invalid expire time in 'set' command"); return}
+			ttl = time.Duration(secs) * time.Second
+		default:
+			s.w.error("ERR syntax error")
+			return
+		}
+	}
+	if nx && xx {s.w.error("ERR syntax error"); return}
+
+	s.tab.mu.Lock()
+	defer s.tab.mu.Unlock()
+
+	if nx || xx {
+		_, ok, _, err := s.getVal(key)
+		if err != nil {s.dbErr(err); return}
+		if (nx && ok) || (xx && !ok) {s.w.null(); return}
+	}
+
+	var deadline time.Time
+	if ttl > 0 {deadline = s.srv.now().Add(ttl)}
+
+	b := s.tab.newBatch()
+	b.put(key, val)
+	b.setDeadline(key, val, deadline)
+	err := b.commit()
+	if err != nil {s.dbErr(err); return}
+	s.w.simple("OK")
+}
+
+// del removes the keys atomically with a batch; expired keys are removed but not counted.
+func (s *session) del(args []string) {
+
+	s.tab.mu.Lock()
+	defer s.tab.mu.Unlock()
+
+	num := int64(0)
+	b := s.tab.newBatch()
+	for _, key := range args {
+		_, ok, expired, err := s.getVal(key)
+		if err != nil {
+			b.rollback()
+			s.dbErr(err)
+			return
+		}
+		if !ok && !expired {continue}
+		b.del(key)
+		b.setDeadline(key, "", time.Time{})
+		if ok {num++}
+	}
+	err := b.commit()
+	if err != nil {s.dbErr(err); return}
+	s.w.int(num)
+}
+
+func (s *session) exists(args []string) {
+
+	num := int64(0)
+	for _, key := range args {
+		_, ok, err := s.read(key)
+		if err != nil {s.dbErr(err); return}
+		if ok {num++}
+	}
+	s.w.int(num)
+}
+
+func (s *session) mget(args []string) {
+
+	vals := make([]*string, len(args))
+	for i, key := range args {
+		val, ok, err := s.read(key)
+		if err != nil {s.dbErr(err); return}
+		if ok {vals[i] = &val}
+	}
+
+	s.w.array(len(vals))
+	for _, val := range vals {
+		if val == nil {
+			s.w.null()
+		} else {
+			s.w.bulk(*val)
+		}
+	}
+}
+
+// mset writes the keys atomically with a batch and removes their expiry times.
+func (s *session) mset(args []string) {
+
+	if len(args) % 2 != 0 {s.w.error("ERR wrong number of arguments for 'mset' command"); return}
+
+	s.tab.mu.Lock()
+	defer s.tab.mu.Unlock()
+
+	b := s.tab.newBatch()
+	for i := 0; i < len(args); i += 2 {
+		b.put(args[i], args[i+1])
+		b.setDeadline(args[i], "", time.Time{})
+	}
+	err := b.commit()
+	if err != nil {s.dbErr(err); return}
+	s.w.simple("OK")
+}
+
+// incr adds 1 to the integer value of key; a missing key is 0. The expiry time is kept.
+func (s *session) incr(args []string) {
+
+	key := args[0]
+	s.tab.mu.Lock()
+	defer s.tab.mu.Unlock()
+
+	val, ok, _, err := s.getVal(key)
+	if err != nil {s.dbErr(err); return}
+
+	num := int64(0)
+	var dl time.Time
+	if ok {
+		num, err = strconv.ParseInt(val, 10, 64)
+		if err != nil {s.w.error("ERR value is not an integer or out of range"); return}
+		dl, _, err = s.tab.deadline(key, val)
+		if err != nil {s.dbErr(err); return}
+	}
+	if num == 1<<63 - 1 {s.w.error("ERR increment or decrement would overflow"); return}
+	num++
+	newVal := strconv.FormatInt(num, 10)
+
+	// the expiry time is written for the new value; an expired key starts again without one
+	b := s.tab.newBatch()
+	b.put(key, newVal)
+	b.setDeadline(key, newVal, dl)
+	err = b.commit()
+	if err != nil {s.dbErr(err); return}
+	s.w.int(num)
+}
+
+// collect returns the keys matching pattern, starting after the key after, up to count keys
+// examined if count > 0. next is the last key examined if there are more keys.
+func (s *session) collect(pattern, after string, count int) (keys []string, next string, err error) {
+
+	opts := []lotusLib.ScanOption{lotusLib.WithPrefix(literalPrefix(pattern)), lotusLib.WithReverse(false)}
+	if len(after) > 0 {opts = append(opts, lotusLib.WithRange(after, ""))}
+
+	it, err := s.tab.db.NewIterator(opts...)
+	if err != nil {return nil, "", err}
+
+	examined := 0
+	last := ""
+	for it.Next() {
+		key := it.Key()
+		if len(after) > 0 && key == after {continue}
+		if count > 0 && examined == count {
+			next = last
+			break
+		}
+		examined++
+		last = key
+		if match(pattern, key) {keys = append(keys, key)}
+	}
+	err = it.Close()
+	if err != nil {return nil, "", err}
+
+	// expired keys are removed after the iterator is closed
+	live := []string{}
+	for _, key := range keys {
+		_, ok, err := s.read(key)
+		if err != nil {return nil, "", err}
+		if ok {live = append(live, key)}
+	}
+	return live, next, nil
+}
+
+func (s *session) keys(args []string) {
+
+	keys, _, err := s.collect(args[0], "", 0)
+	if err != nil {s.dbErr(err); return}
+	s.w.bulks(keys)
+}
+
+// scan returns the next keys for cursor. A cursor other than 0 is the id of a position
+// kept by the server; it stays valid until it is dropped after maxCursors.
+func (s *session) scan(args []string) {
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {s.w.error("ERR invalid cursor"); return}
+
+	pattern := "*"
+	count := 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {s.w.error("ERR syntax error"); return}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {s.w.error("ERR value is not an integer or out of range"); return}
+		default:
+			s.w.error("ERR syntax error")
+			return
+		}
+	}
+
+	after := ""
+	if id != 0 {
+		key, ok := s.srv.cursor(id, s.tab)
+		if !ok {s.w.error("ERR invalid cursor"); return}
+		after = key
+	}
+
+	keys, next, err := s.collect(pattern, after, count)
+	if err != nil {s.dbErr(err); return}
+
+	nextId := uint64(0)
+	if len(next) > 0 {
+		nextId, err = s.srv.newCursor(s.tab, next)
+		if err != nil {s.dbErr(err); return}
+	}
+
+	s.w.array(2)
+	s.w.bulk(strconv.FormatUint(nextId, 10))
+	s.w.bulks(keys)
+}
+
+// dbsize returns the number of keys of the table; expired keys are removed first.
+func (s *session) dbsize(args []string) {
+
+	err := s.tab.purge(s.srv.now())
+	if err != nil {s.dbErr(err); return}
+
+	num, err := s.tab.db.Count(lotusLib.WithPrefix(""))
+	if err != nil {s.dbErr(err); return}
+	s.w.int(int64(num))
+}
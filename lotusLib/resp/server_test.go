@@ -0,0 +1,414 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prr123/lotusdb/lotusLib"
+	"github.com/prr123/lotusdb/lotusLib/server"
+)
+
+// client is a minimal resp2 client.
+type client struct {
+	t *testing.T
+	conn net.Conn
+	r *bufio.Reader
+}
+
+// initCatalog returns a catalog with the tables alpha and beta.
+func initCatalog(t *testing.T) (cat *lotusLib.Catalog) {
+
+	cat, err := lotusLib.OpenCatalog(t.TempDir(), false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+	t.Cleanup(func() {cat.Close()})
+	for _, name := range []string{"alpha", "beta"} {
+		_, err = cat.Create(name, "")
+		if err != nil {t.Fatalf("error -- Create %s: %v", name, err)}
+	}
+	return cat
+}
+
+// initServer serves a catalog with the tables alpha and beta.
+func initServer(t *testing.T) (srv *Server, addr string) {
+
+	srv = New(initCatalog(t), "alpha")
+	return srv, serve(t, srv)
+}
+
+// serve serves srv on a local port until the end of the test.
+func serve(t *testing.T, srv *Server) (addr string) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {t.Fatalf("error -- Listen: %v", err)}
+	done := make(chan error, 1)
+	go func() {done <- srv.Serve(ln)}()
+	t.Cleanup(func() {
+		srv.Close()
+		err := <-done
+		if err != ErrServerClosed {t.Errorf("error -- Serve after Close: %v", err)}
+	})
+	return ln.Addr().String()
+}
+
+func dial(t *testing.T, addr string) (cl *client) {
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {t.Fatalf("error -- Dial: %v", err)}
+	t.Cleanup(func() {conn.Close()})
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends a command and returns the reply as text: OK for +OK, -ERR ... for errors,
+// 3 for :3, the string of a bulk string, (nil) and [a b] for arrays.
+func (cl *client) do(args ...string) string {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(cl.conn, sb.String())
+	if err != nil {cl.t.Fatalf("error -- write %v: %v", args, err)}
+	return cl.reply()
+}
+
+func (cl *client) reply() string {
+
+	line, err := cl.r.ReadString('\n')
+	if err != nil {cl.t.Fatalf("error -- read reply: %v", err)}
+	line = strings.TrimRight(line, "\r\n")
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:]
+	case '-':
+		return line
+	case '$':
+		num, _ := strconv.Atoi(line[1:])
+		if num < 0 {return "(nil)"}
+		data := make([]byte, num + 2)
+		_, err = io.ReadFull(cl.r, data)
+		if err != nil {cl.t.Fatalf("error -- read bulk: %v", err)}
+		return string(data[:num])
+	case '*':
+		num, _ := strconv.Atoi(line[1:])
+		list := []string{}
+		for i := 0; i < num; i++ {
+			list = append(list, cl.reply())
+		}
+		return fmt.Sprint(list)
+	}
+	cl.t.Fatalf("error -- invalid reply %q", line)
+	return ""
+}
+
+func TestCommands(t *testing.T) {
+
+	_, addr := initServer(t)
+	cl := dial(t, addr)
+
+	tests := []struct {
+		args []string
+		reply string
+	}{
+		{[]string{"PING"}, "PONG"},
+		{[]string{"ping", "hi"}, "hi"},
+		{[]string{"SET", "user:1", "alice"}, "OK"},
+		{[]string{"GET", "user:1"}, "alice"},
+		{[]string{"GET", "user:9"}, "(nil)"},
+		{[]string{"SET", "user:1", "bob", "NX"}, "(nil)"},
+		{[]string{"SET", "user:2", "bob", "XX"}, "(nil)"},
+		{[]string{"SET", "user:2", "bob", "nx"}, "OK"},
+		{[]string{"SET", "user:2", "carol", "XX"}, "OK"},
+		{[]string{"SET", "user:2", "x", "NX", "XX"}, "-ERR syntax error"},
+		{[]string{"SET", "user:2", "x", "EX", "0"}, "-ERR invalid expire time in 'set' command"},
+		{[]string{"GET", "user:2"}, "carol"},
+		{[]string{"MSET", "n:1", "1", "n:2", "2", "n:3", "3"}, "OK"},
+		{[]string{"MSET", "n:1", "1", "n:2"}, "-ERR wrong number of arguments for 'mset' command"},
+		{[]string{"MGET", "n:1", "n:9", "user:1"}, "[1 (nil) alice]"},
+		{[]string{"EXISTS", "n:1", "n:9", "n:1"}, "2"},
+		{[]string{"INCR", "n:2"}, "3"},
+		{[]string{"INCR", "counter"}, "1"},
+		{[]string{"INCR", "user:1"}, "-ERR value is not an integer or out of range"},
+		{[]string{"KEYS", "n:*"}, "[n:1 n:2 n:3]"},
+		{[]string{"KEYS", "*:[12]"}, "[n:1 n:2 user:1 user:2]"},
+		{[]string{"DBSIZE"}, "6"},
+		{[]string{"DEL", "n:1", "n:9", "counter"}, "2"},
+		{[]string{"DBSIZE"}, "4"},
+		{[]string{"GET"}, "-ERR wrong number of arguments for 'get' command"},
+		{[]string{"FLUSHALL"}, "-ERR unknown command 'FLUSHALL'"},
+	}
+
+	for _, tc := range tests {
+		reply := cl.do(tc.args...)
+		if reply != tc.reply {t.Errorf("error -- %v: %q expected %q", tc.args, reply, tc.reply)}
+	}
+}
+
+func TestSelect(t *testing.T) {
+
+	_, addr := initServer(t)
+	cl := dial(t, addr)
+
+	steps := [][2]string{
+		{"SET k alpha", "OK"},
+		{"SELECT beta", "OK"},
+		{"GET k", "(nil)"},
+		{"SET k beta", "OK"},
+		{"SELECT 0", "OK"},
+		{"GET k", "alpha"},
+		{"SELECT 1", "OK"},
+		{"GET k", "beta"},
+		{"SELECT nosuch", "-ERR Open nosuch: table not found"},
+		{"GET k", "beta"},
+	}
+	for _, step := range steps {
+		reply := cl.do(strings.Fields(step[0])...)
+		if reply != step[1] {t.Errorf("error -- %s: %q expected %q", step[0], reply, step[1])}
+	}
+
+	info := cl.do("INFO")
+	if !strings.Contains(info, "lotus_table:beta") || !strings.Contains(info, "db1:table=beta") || !strings.Contains(info, "connected_clients:1") {t.Errorf("error -- INFO:\n%s", info)}
+}
+
+func TestScan(t *testing.T) {
+
+	srv, addr := initServer(t)
+	cl := dial(t, addr)
+
+	for i := 0; i < 25; i++ {
+		cl.do("SET", fmt.Sprintf("key:%02d", i), "v")
+	}
+	cl.do("SET", "other", "v")
+
+	for _, tc := range []struct {
+		args []string
+		num int
+	}{
+		{[]string{}, 26},
+		{[]string{"MATCH", "key:*", "COUNT", "7"}, 25},
+		{[]string{"MATCH", "key:1?"}, 10},
+		{[]string{"COUNT", "100"}, 26},
+	} {
+		cursor := "0"
+		seen := map[string]bool{}
+		for n := 0; n < 100; n++ {
+			args := append([]string{"SCAN", cursor}, tc.args...)
+			fmt.Fprintf(cl.conn, "*%d\r\n", len(args))
+			for _, arg := range args {
+				fmt.Fprintf(cl.conn, "$%d\r\n%s\r\n", len(arg), arg)
+			}
+			header := cl.reply0()
+			if header != "*2" {t.Fatalf("error -- SCAN %v: reply %q", args, header)}
+			cursor = cl.reply()
+			keys := strings.Fields(strings.Trim(cl.reply(), "[]"))
+			for _, key := range keys {
+				if seen[key] {t.Errorf("error -- SCAN %v returned %s twice", tc.args, key)}
+				seen[key] = true
+			}
+			if cursor == "0" {break}
+		}
+		if len(seen) != tc.num {t.Errorf("error -- SCAN %v: %d keys expected %d", tc.args, len(seen), tc.num)}
+	}
+
+	if reply := cl.do("SCAN", "12345"); reply != "-ERR invalid cursor" {t.Errorf("error -- SCAN unknown cursor: %q", reply)}
+
+	// a pooled client continues a SCAN on another connection; the cursor belongs to its table
+	cursor := strings.Fields(strings.Trim(cl.do("SCAN", "0", "COUNT", "5"), "[]"))[0]
+	if reply := dial(t, addr).do("SCAN", cursor, "COUNT", "100"); !strings.HasPrefix(reply, "[0 ") {t.Errorf("error -- SCAN cursor on another connection: %q", reply)}
+	if reply := cl.do("SCAN", cursor, "COUNT", "100"); !strings.HasPrefix(reply, "[0 ") {t.Errorf("error -- SCAN cursor used again: %q", reply)}
+	other := dial(t, addr)
+	other.do("SELECT", "beta")
+	if reply := other.do("SCAN", cursor); reply != "-ERR invalid cursor" {t.Errorf("error -- SCAN cursor of another table: %q", reply)}
+	if reply := cl.do("SCAN", "0", "COUNT"); reply != "-ERR syntax error" {t.Errorf("error -- SCAN without count: %q", reply)}
+
+	// the least recently used cursors are dropped
+	for i := 0; i < maxCursors; i++ {
+		cl.do("SCAN", "0", "COUNT", "1")
+	}
+	if reply := cl.do("SCAN", cursor); reply != "-ERR invalid cursor" {t.Errorf("error -- SCAN cursor after maxCursors: %q", reply)}
+	srv.curMu.Lock()
+	num := len(srv.cursors)
+	srv.curMu.Unlock()
+	if num != maxCursors {t.Errorf("error -- %d cursors kept expected %d", num, maxCursors)}
+}
+
+// reply0 reads the first line of a reply.
+func (cl *client) reply0() string {
+
+	line, err := cl.r.ReadString('\n')
+	if err != nil {cl.t.Fatalf("error -- read reply: %v", err)}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// testClock is the clock of a server, it only moves with add.
+type testClock struct {
+	mu sync.Mutex
+	t time.Time
+}
+
+func (clk *testClock) now() time.Time {
+
+	clk.mu.Lock()
+	defer clk.mu.Unlock()
+	return clk.t
+}
+
+func (clk *testClock) add(d time.Duration) {
+
+	clk.mu.Lock()
+	clk.t = clk.t.Add(d)
+	clk.mu.Unlock()
+}
+
+func TestExpire(t *testing.T) {
+
+	clk := &testClock{t: time.Now()}
+	srv := New(initCatalog(t), "alpha")
+	srv.now = clk.now
+	cl := dial(t, serve(t, srv))
+
+	cl.do("SET", "session", "abc", "EX", "10")
+	cl.do("SET", "keep", "x", "EX", "10")
+	cl.do("SET", "keep", "y")
+	cl.do("SET", "count", "1", "EX", "10")
+	cl.do("INCR", "count")
+
+	if reply := cl.do("KEYS", "*"); reply != "[count keep session]" {t.Errorf("error -- KEYS shows the expiry times: %q", reply)}
+	if reply := cl.do("DBSIZE"); reply != "3" {t.Errorf("error -- DBSIZE counts the expiry times: %q", reply)}
+
+	clk.add(11 * time.Second)
+
+	if reply := cl.do("GET", "session"); reply != "(nil)" {t.Errorf("error -- GET expired key: %q", reply)}
+	if reply := cl.do("GET", "keep"); reply != "y" {t.Errorf("error -- SET without EX kept the expiry: %q", reply)}
+	if reply := cl.do("EXISTS", "count"); reply != "0" {t.Errorf("error -- INCR removed the expiry: %q", reply)}
+	if reply := cl.do("SET", "session", "new", "NX"); reply != "OK" {t.Errorf("error -- SET NX on expired key: %q", reply)}
+	if reply := cl.do("DBSIZE"); reply != "2" {t.Errorf("error -- DBSIZE: %q", reply)}
+
+
+	// the expiry times are not in the table
+	db, err := srv.Cat.Open("alpha")
+	if err != nil {t.Fatalf("error -- Open: %v", err)}
+	num, err := db.Count(lotusLib.WithPrefix(""))
+	if err != nil || num != 2 {t.Errorf("error -- entries of the table: %d %v", num, err)}
+	if list := srv.Cat.List(); len(list) != 2 {t.Errorf("error -- catalog lists the expiry times: %+v", list)}
+}
+
+func TestExpireOtherWriter(t *testing.T) {
+
+	cat := initCatalog(t)
+	clk := &testClock{t: time.Now()}
+	srv := New(cat, "alpha")
+	srv.now = clk.now
+	cl := dial(t, serve(t, srv))
+
+	cl.do("SET", "session", "abc", "EX", "10")
+	cl.do("SET", "gone", "abc", "EX", "10")
+
+	// the http server writes and deletes the keys without the expiry times
+	hsrv := server.New(cat)
+	rec := httptest.NewRecorder()
+	hsrv.ServeHTTP(rec, httptest.NewRequest("PUT", "/tables/alpha/keys/session", strings.NewReader("new")))
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {t.Fatalf("error -- http PUT: %d %s", rec.Code, rec.Body)}
+	rec = httptest.NewRecorder()
+	hsrv.ServeHTTP(rec, httptest.NewRequest("DELETE", "/tables/alpha/keys/gone", nil))
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {t.Fatalf("error -- http DELETE: %d %s", rec.Code, rec.Body)}
+
+	// a key written since the SET EX survives the old deadline
+	clk.add(11 * time.Second)
+	if reply := cl.do("GET", "session"); reply != "new" {t.Errorf("error -- GET of a key written over http: %q", reply)}
+	if reply := cl.do("DBSIZE"); reply != "1" {t.Errorf("error -- DBSIZE: %q", reply)}
+	rec = httptest.NewRecorder()
+	hsrv.ServeHTTP(rec, httptest.NewRequest("PUT", "/tables/alpha/keys/gone", strings.NewReader("abc")))
+	if reply := cl.do("GET", "gone"); reply != "abc" {t.Errorf("error -- GET of a key created again over http: %q", reply)}
+}
+
+func TestExpireRestart(t *testing.T) {
+
+	cat := initCatalog(t)
+	clk := &testClock{t: time.Now()}
+	srv := New(cat, "alpha")
+	srv.now = clk.now
+	cl := dial(t, serve(t, srv))
+
+	cl.do("SET", "short", "a", "EX", "10")
+	cl.do("SET", "long", "b", "EX", "100")
+	cl.do("SET", "plain", "c")
+	srv.Close()
+
+	// a new server on the same catalog keeps the expiry times and removes the expired keys
+	clk.add(11 * time.Second)
+	srv = New(cat, "alpha")
+	srv.now = clk.now
+	cl = dial(t, serve(t, srv))
+
+	if reply := cl.do("MGET", "short", "long", "plain"); reply != "[(nil) b c]" {t.Errorf("error -- MGET after restart: %q", reply)}
+	db, err := cat.Open("alpha")
+	if err != nil {t.Fatalf("error -- Open: %v", err)}
+	_, err = db.Get([]byte("short"))
+	if !errors.Is(err, lotusLib.ErrKeyNotFound) {t.Errorf("error -- expired key kept after restart: %v", err)}
+
+	clk.add(90 * time.Second)
+	if reply := cl.do("GET", "long"); reply != "(nil)" {t.Errorf("error -- expiry time lost on restart: %q", reply)}
+	if reply := cl.do("DBSIZE"); reply != "1" {t.Errorf("error -- DBSIZE after restart: %q", reply)}
+}
+
+func TestDefaultTable(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {t.Fatalf("error -- Listen: %v", err)}
+	err = New(initCatalog(t), "nosuch").Serve(ln)
+	if !errors.Is(err, lotusLib.ErrTableNotFound) {t.Errorf("error -- Serve with an unknown default table: %v", err)}
+}
+
+func TestConcurrentIncr(t *testing.T) {
+
+	_, addr := initServer(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		cl := dial(t, addr)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				cl.do("INCR", "counter")
+				cl.do("GET", "counter")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reply := dial(t, addr).do("GET", "counter"); reply != "200" {t.Errorf("error -- concurrent INCR: %q", reply)}
+}
+
+func TestInlineAndPipeline(t *testing.T) {
+
+	_, addr := initServer(t)
+	cl := dial(t, addr)
+
+	io.WriteString(cl.conn, "SET a 1\r\nINCR a\r\nGET a\r\n")
+	for _, want := range []string{"OK", "2", "2"} {
+		if reply := cl.reply(); reply != want {t.Errorf("error -- pipelined reply: %q expected %q", reply, want)}
+	}
+
+	io.WriteString(cl.conn, "*1\r\n:1\r\n")
+	if reply := cl.reply(); !strings.HasPrefix(reply, "-ERR Protocol error") {t.Errorf("error -- protocol error reply: %q", reply)}
+	_, err := cl.r.ReadByte()
+	if err == nil {t.Errorf("error -- connection open after a protocol error")}
+
+	cl = dial(t, addr)
+	if reply := cl.do("QUIT"); reply != "OK" {t.Errorf("error -- QUIT: %q", reply)}
+	_, err = cl.r.ReadByte()
+	if err == nil {t.Errorf("error -- connection open after QUIT")}
+}
@@ -0,0 +1,203 @@
+// scan
+// range scans over a lotusdb table
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"iter"
+	"strings"
+
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// ScanOpt selects the keys returned by Scan, Seq and NewIterator.
+type ScanOpt struct {
+	// Prefix only selects keys starting with Prefix.
+	Prefix string
+
+	// Reverse scans from the highest to the lowest key.
+	Reverse bool
+
+	// Start is the first key of the scan (inclusive).
+	// In reverse order it is the highest key of the scan.
+	Start string
+
+	// End stops the scan (exclusive). In reverse order it is the lower bound.
+	End string
+
+	// Limit is the maximum number of entries; 0 means no limit.
+	Limit int
+}
+
+// ScanOption modifies the ScanOpt defaults taken from DBObj.IterOpt.
+type ScanOption func(opt *ScanOpt)
+
+func WithPrefix(prefix string) ScanOption {
+	return func(opt *ScanOpt) {opt.Prefix = prefix}
+}
+
+func WithReverse(reverse bool) ScanOption {
+	return func(opt *ScanOpt) {opt.Reverse = reverse}
+}
+
+// WithRange limits the scan to keys from start up to end. An empty string leaves the bound open.
+func WithRange(start, end string) ScanOption {
+	return func(opt *ScanOpt) {
+		opt.Start = start
+		opt.End = end
+	}
+}
+
+func WithLimit(limit int) ScanOption {
+	return func(opt *ScanOpt) {opt.Limit = limit}
+}
+
+// ScanOptions returns the scan options for opts, starting from the IterOpt defaults.
+func (dbp *DBObj) ScanOptions (opts ...ScanOption) (opt ScanOpt){
+
+	dbp.optMu.RLock()
+	opt.Prefix = string(dbp.IterOpt.Prefix)
+	opt.Reverse = dbp.IterOpt.Reverse
+	dbp.optMu.RUnlock()
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// Iterator walks the entries of a scan:
+//
+//	it, err := db.NewIterator(WithPrefix("user:"))
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Key(), it.Value())
+//	}
+type Iterator struct {
+	iter *lotusdb.MergeIterator
+	opt ScanOpt
+	started bool
+	done bool
+	count int
+}
+
+func (dbp *DBObj) NewIterator (opts ...ScanOption) (it *Iterator, err error){
+
+	opt := dbp.ScanOptions(opts...)
+
+	db := (*dbp).Db
+	iterOpt := lotusdb.IteratorOptions{
+		Prefix: []byte(opt.Prefix),
+		Reverse: opt.Reverse,
+	}
+	lit, err := db.NewIterator(iterOpt)
+	if err != nil {return nil, dbErr("NewIterator", err)}
+
+	lit.Rewind()
+	if len(opt.Start) > 0 {lit.Seek([]byte(opt.Start))}
+
+	it = &Iterator{
+		iter: lit,
+		opt: opt,
+	}
+	return it, nil
+}
+
+// Next advances to the next entry. It returns false once the scan is exhausted.
+func (it *Iterator) Next () bool {
+
+	if it.done {return false}
+	if it.opt.Limit > 0 && it.count >= it.opt.Limit {
+		it.done = true
+		return false
+	}
+
+	for {
+		if it.started {
+			it.iter.Next()
+		} else {
+			it.started = true
+		}
+		if !it.iter.Valid() {
+			it.done = true
+			return false
+		}
+
+		key := string(it.iter.Key())
+		if !strings.HasPrefix(key, it.opt.Prefix) {continue}
+		if len(it.opt.End) > 0 {
+			if (!it.opt.Reverse && key >= it.opt.End) || (it.opt.Reverse && key <= it.opt.End) {
+				it.done = true
+				return false
+			}
+		}
+		it.count++
+		return true
+	}
+}
+
+func (it *Iterator) Key () string {
+	return string(it.iter.Key())
+}
+
+func (it *Iterator) Value () string {
+	return string(it.iter.Value())
+}
+
+func (it *Iterator) Close () (err error){
+
+	it.done = true
+	err = it.iter.Close()
+	if err != nil {return dbErr("Iterator Close", err)}
+	return nil
+}
+
+// Scan returns the keys and values selected by opts.
+func (dbp *DBObj) Scan (opts ...ScanOption) (keyList, valList []string, err error){
+
+	it, err := dbp.NewIterator(opts...)
+	if err != nil {return nil, nil, err}
+
+	for it.Next() {
+		keyList = append(keyList, it.Key())
+		valList = append(valList, it.Value())
+	}
+
+	err = it.Close()
+	return keyList, valList, err
+}
+
+// Count returns the number of entries selected by opts.
+func (dbp *DBObj) Count (opts ...ScanOption) (n int, err error){
+
+	it, err := dbp.NewIterator(opts...)
+	if err != nil {return 0, err}
+
+	for it.Next() {
+		n++
+	}
+
+	err = it.Close()
+	return n, err
+}
+
+// Seq returns the scan selected by opts as a range-over-func sequence:
+//
+//	for key, val := range db.Seq(WithLimit(10)) {...}
+//
+// An error ends the sequence; use NewIterator when errors have to be reported.
+func (dbp *DBObj) Seq (opts ...ScanOption) iter.Seq2[string, string] {
+
+	return func(yield func(string, string) bool) {
+		it, err := dbp.NewIterator(opts...)
+		if err != nil {return}
+		defer it.Close()
+
+		for it.Next() {
+			if !yield(it.Key(), it.Value()) {return}
+		}
+	}
+}
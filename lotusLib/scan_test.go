@@ -0,0 +1,90 @@
+package lotusLib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func initScanDb(t *testing.T) (db *DBObj) {
+
+	db, err := InitDb(t.TempDir(), "ScanDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+
+	for _, key := range []string{"a1", "b1", "b2", "b3", "b4", "c1"} {
+		err = db.AddEntry(key, "val_" + key)
+		if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+	}
+	return db
+}
+
+func TestScan(t *testing.T) {
+
+	db := initScanDb(t)
+	defer db.Close()
+
+	tests := []struct {
+		name string
+		opts []ScanOption
+		keys string
+	}{
+		{"all", nil, "[a1 b1 b2 b3 b4 c1]"},
+		{"prefix", []ScanOption{WithPrefix("b")}, "[b1 b2 b3 b4]"},
+		{"reverse", []ScanOption{WithReverse(true)}, "[c1 b4 b3 b2 b1 a1]"},
+		{"range", []ScanOption{WithRange("b2", "c1")}, "[b2 b3 b4]"},
+		{"reverse range", []ScanOption{WithReverse(true), WithRange("b3", "a1")}, "[b3 b2 b1]"},
+		{"open end", []ScanOption{WithRange("b4", "")}, "[b4 c1]"},
+		{"limit", []ScanOption{WithPrefix("b"), WithLimit(2)}, "[b1 b2]"},
+		{"no match", []ScanOption{WithPrefix("x")}, "[]"},
+	}
+
+	for _, tc := range tests {
+		keyList, valList, err := db.Scan(tc.opts...)
+		if err != nil {t.Errorf("error -- %s Scan: %v", tc.name, err); continue}
+		if fmt.Sprint(keyList) != tc.keys {t.Errorf("error -- %s Scan keys: %v expected %s", tc.name, keyList, tc.keys)}
+		for i := range keyList {
+			if valList[i] != "val_" + keyList[i] {t.Errorf("error -- %s Scan value for %s: %s", tc.name, keyList[i], valList[i])}
+		}
+	}
+}
+
+func TestScanDefaults(t *testing.T) {
+
+	db := initScanDb(t)
+	defer db.Close()
+
+	db.IterOpt.Prefix = []byte("b")
+	db.IterOpt.Reverse = true
+
+	keyList, _, err := db.Scan()
+	if err != nil {t.Fatalf("error -- Scan: %v", err)}
+	if fmt.Sprint(keyList) != "[b4 b3 b2 b1]" {t.Errorf("error -- Scan with IterOpt defaults: %v", keyList)}
+
+	keyList, _, err = db.Scan(WithReverse(false), WithLimit(1))
+	if err != nil {t.Fatalf("error -- Scan: %v", err)}
+	if fmt.Sprint(keyList) != "[b1]" {t.Errorf("error -- Scan overriding IterOpt: %v", keyList)}
+}
+
+func TestSeq(t *testing.T) {
+
+	db := initScanDb(t)
+	defer db.Close()
+
+	keyList := []string{}
+	for key, val := range db.Seq(WithPrefix("b")) {
+		if val != "val_" + key {t.Errorf("error -- Seq value for %s: %s", key, val)}
+		keyList = append(keyList, key)
+		if len(keyList) == 3 {break}
+	}
+	if fmt.Sprint(keyList) != "[b1 b2 b3]" {t.Errorf("error -- Seq keys: %v", keyList)}
+}
+
+func TestCount(t *testing.T) {
+
+	db := initScanDb(t)
+	defer db.Close()
+
+	n, err := db.Count()
+	if err != nil || n != 6 {t.Errorf("error -- Count: %d %v expected 6", n, err)}
+	n, err = db.Count(WithPrefix("b"), WithLimit(3))
+	if err != nil || n != 3 {t.Errorf("error -- Count with prefix and limit: %d %v expected 3", n, err)}
+}
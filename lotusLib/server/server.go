@@ -0,0 +1,358 @@
+// server
+// http rest server for the tables of a catalog
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+// Server serves the tables of a lotusLib Catalog:
+//
+//	GET    /tables                       list the tables
+//	GET    /tables/{tab}/keys/{key}      value of key
+//	HEAD   /tables/{tab}/keys/{key}      200 if key exists, 404 otherwise
+//	PUT    /tables/{tab}/keys/{key}      set the value of key to the request body
+//	DELETE /tables/{tab}/keys/{key}      delete key
+//	GET    /tables/{tab}/scan?prefix=&reverse=&limit=&cursor=
+//	POST   /tables/{tab}/batch           atomic puts and deletes
+//	POST   /tables/{tab}/batch/get       values of several keys
+//
+// Keys are the rest of the path and may contain /. Values are sent as the raw
+// request and response body; the json bodies use the entries of the lotusLib
+// dump format, {"key":..., "value":...} with key64 and value64 for keys and
+// values that are not valid utf-8. Errors are returned as {"error":...}.
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+// Server is the http.Handler of the rest api.
+type Server struct {
+	Cat *lotusLib.Catalog
+
+	// PageSize is the number of scan entries if the request has no limit.
+	PageSize int
+	// MaxPageSize is the highest scan limit; larger limits are reduced to it.
+	MaxPageSize int
+	// MaxBodySize is the maximum size of a request body.
+	MaxBodySize int64
+
+	mux *http.ServeMux
+}
+
+// New returns a server for the tables of cat.
+func New(cat *lotusLib.Catalog) (srv *Server) {
+
+	srv = &Server{
+		Cat: cat,
+		PageSize: 100,
+		MaxPageSize: 1000,
+		MaxBodySize: 32 << 20,
+		mux: http.NewServeMux(),
+	}
+	srv.mux.HandleFunc("GET /tables", srv.tables)
+	srv.mux.HandleFunc("GET /tables/{tab}/keys/{key...}", srv.get)
+	srv.mux.HandleFunc("PUT /tables/{tab}/keys/{key...}", srv.put)
+	srv.mux.HandleFunc("DELETE /tables/{tab}/keys/{key...}", srv.del)
+	srv.mux.HandleFunc("GET /tables/{tab}/scan", srv.scan)
+	srv.mux.HandleFunc("POST /tables/{tab}/batch", srv.batch)
+	srv.mux.HandleFunc("POST /tables/{tab}/batch/get", srv.batchGet)
+	return srv
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mux.ServeHTTP(w, r)
+}
+
+// Entry is a key and its value in a json body.
+type Entry struct {
+	Key string `json:"key,omitempty"`
+	Key64 []byte `json:"key64,omitempty"`
+	Val string `json:"value,omitempty"`
+	Val64 []byte `json:"value64,omitempty"`
+}
+
+func newEntry(key, val string) (ent Entry) {
+
+	if utf8.ValidString(key) {
+		ent.Key = key
+	} else {
+		ent.Key64 = []byte(key)
+	}
+	if utf8.ValidString(val) {
+		ent.Val = val
+	} else {
+		ent.Val64 = []byte(val)
+	}
+	return ent
+}
+
+func (ent *Entry) key() string {
+	if len(ent.Key64) > 0 {return string(ent.Key64)}
+	return ent.Key
+}
+
+func (ent *Entry) val() string {
+	if len(ent.Val64) > 0 {return string(ent.Val64)}
+	return ent.Val
+}
+
+// ScanPage is the response of scan. Cursor is empty on the last page;
+// otherwise it is passed as cursor to get the next page.
+type ScanPage struct {
+	Entries []Entry `json:"entries"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// BatchOp is an operation of a batch: "put" or "delete".
+type BatchOp struct {
+	Op string `json:"op"`
+	Entry
+}
+
+type BatchReq struct {
+	Ops []BatchOp `json:"ops"`
+}
+
+type BatchResp struct {
+	Puts int `json:"puts"`
+	Deletes int `json:"deletes"`
+}
+
+type BatchGetReq struct {
+	Keys []string `json:"keys"`
+}
+
+// BatchGetResp lists the entries of the keys found and the keys that are missing.
+type BatchGetResp struct {
+	Entries []Entry `json:"entries"`
+	Missing []string `json:"missing"`
+}
+
+// errBadRequest marks errors in the request.
+var errBadRequest = errors.New("bad request")
+
+// status returns the http status of err.
+func status(err error) int {
+
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errBadRequest), errors.Is(err, lotusLib.ErrKeyEmpty):
+		return http.StatusBadRequest
+	case errors.Is(err, lotusLib.ErrKeyNotFound), errors.Is(err, lotusLib.ErrTableNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, lotusLib.ErrReadOnly):
+		return http.StatusConflict
+	case errors.Is(err, lotusLib.ErrClosed), errors.Is(err, lotusLib.ErrDbLocked):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &maxErr):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	writeJSON(w, status(err), map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// readJSON decodes the request body into v.
+func (srv *Server) readJSON(w http.ResponseWriter, r *http.Request, v any) (err error) {
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, srv.MaxBodySize))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(v)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {return err}
+	if err != nil {return fmt.Errorf("%w: %v", errBadRequest, err)}
+	return nil
+}
+
+// table returns the table and key of the request.
+func (srv *Server) table(r *http.Request) (db *lotusLib.DBObj, key string, err error) {
+
+	db, err = srv.Cat.Open(r.PathValue("tab"))
+	if err != nil {return nil, "", err}
+	return db, r.PathValue("key"), nil
+}
+
+func (srv *Server) tables(w http.ResponseWriter, r *http.Request) {
+
+	list := []string{}
+	for _, info := range srv.Cat.List() {
+		list = append(list, info.Name)
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"tables": list})
+}
+
+// get returns the value of the key; for HEAD only the status.
+func (srv *Server) get(w http.ResponseWriter, r *http.Request) {
+
+	db, key, err := srv.table(r)
+	if err != nil {writeErr(w, err); return}
+
+	if r.Method == http.MethodHead {
+		res, err := db.Exists([]byte(key))
+		if err != nil {writeErr(w, err); return}
+		if !res {w.WriteHeader(http.StatusNotFound); return}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	val, err := db.Get([]byte(key))
+	if err != nil {writeErr(w, err); return}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(val)))
+	w.Write(val)
+}
+
+func (srv *Server) put(w http.ResponseWriter, r *http.Request) {
+
+	db, key, err := srv.table(r)
+	if err != nil {writeErr(w, err); return}
+
+	val, err := io.ReadAll(http.MaxBytesReader(w, r.Body, srv.MaxBodySize))
+	if err != nil {writeErr(w, err); return}
+
+	err = db.Put([]byte(key), val)
+	if err != nil {writeErr(w, err); return}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *Server) del(w http.ResponseWriter, r *http.Request) {
+
+	db, key, err := srv.table(r)
+	if err != nil {writeErr(w, err); return}
+
+	err = db.Delete([]byte(key))
+	if err != nil {writeErr(w, err); return}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scan returns a page of the entries with prefix. The cursor is the last key of
+// the previous page, base64 url encoded; the page starts after it.
+func (srv *Server) scan(w http.ResponseWriter, r *http.Request) {
+
+	db, _, err := srv.table(r)
+	if err != nil {writeErr(w, err); return}
+
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+
+	reverse := false
+	if str := query.Get("reverse"); len(str) > 0 {
+		reverse, err = strconv.ParseBool(str)
+		if err != nil {writeErr(w, fmt.Errorf("%w: reverse: %v", errBadRequest, err)); return}
+	}
+
+	limit := srv.PageSize
+	if str := query.Get("limit"); len(str) > 0 {
+		limit, err = strconv.Atoi(str)
+		if err != nil || limit < 1 {writeErr(w, fmt.Errorf("%w: limit must be a positive integer", errBadRequest)); return}
+	}
+	if limit > srv.MaxPageSize {limit = srv.MaxPageSize}
+
+	opts := []lotusLib.ScanOption{lotusLib.WithPrefix(prefix), lotusLib.WithReverse(reverse)}
+	after := ""
+	hasCursor := len(query.Get("cursor")) > 0
+	if hasCursor {
+		keydat, err := base64.RawURLEncoding.DecodeString(query.Get("cursor"))
+		if err != nil {writeErr(w, fmt.Errorf("%w: cursor: %v", errBadRequest, err)); return}
+		after = string(keydat)
+		if !strings.HasPrefix(after, prefix) {writeErr(w, fmt.Errorf("%w: cursor does not match the prefix", errBadRequest)); return}
+		opts = append(opts, lotusLib.WithRange(after, ""))
+	}
+
+	it, err := db.NewIterator(opts...)
+	if err != nil {writeErr(w, err); return}
+	defer it.Close()
+
+	page := ScanPage{Entries: []Entry{}}
+	last := ""
+	for it.Next() {
+		key := it.Key()
+		if hasCursor && key == after {continue}
+		if len(page.Entries) == limit {
+			page.Cursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		page.Entries = append(page.Entries, newEntry(key, it.Value()))
+		last = key
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// batch applies the operations of the request atomically; if an operation is invalid none is applied.
+func (srv *Server) batch(w http.ResponseWriter, r *http.Request) {
+
+	db, _, err := srv.table(r)
+	if err != nil {writeErr(w, err); return}
+
+	var req BatchReq
+	err = srv.readJSON(w, r, &req)
+	if err != nil {writeErr(w, err); return}
+
+	var resp BatchResp
+	b := db.NewBatch()
+	for i, op := range req.Ops {
+		switch op.Op {
+		case "put":
+			err = b.Put([]byte(op.key()), []byte(op.val()))
+			if err == nil {resp.Puts++}
+		case "delete":
+			err = b.Delete([]byte(op.key()))
+			if err == nil {resp.Deletes++}
+		default:
+			err = fmt.Errorf("%w: unknown op %q (put, delete)", errBadRequest, op.Op)
+		}
+		if err != nil {
+			b.Rollback()
+			writeErr(w, fmt.Errorf("op %d: %w", i, err))
+			return
+		}
+	}
+
+	err = b.Commit()
+	if err != nil {writeErr(w, err); return}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (srv *Server) batchGet(w http.ResponseWriter, r *http.Request) {
+
+	db, _, err := srv.table(r)
+	if err != nil {writeErr(w, err); return}
+
+	var req BatchGetReq
+	err = srv.readJSON(w, r, &req)
+	if err != nil {writeErr(w, err); return}
+
+	resp := BatchGetResp{Entries: []Entry{}, Missing: []string{}}
+	for _, key := range req.Keys {
+		val, err := db.Get([]byte(key))
+		if errors.Is(err, lotusLib.ErrKeyNotFound) {
+			resp.Missing = append(resp.Missing, key)
+			continue
+		}
+		if err != nil {writeErr(w, err); return}
+		resp.Entries = append(resp.Entries, newEntry(key, string(val)))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
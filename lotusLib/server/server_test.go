@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prr123/lotusdb/lotusLib"
+)
+
+func initServer(t *testing.T) (ts *httptest.Server) {
+
+	cat, err := lotusLib.OpenCatalog(t.TempDir(), false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+	t.Cleanup(func() {cat.Close()})
+
+	db, err := cat.Create("users", "test table")
+	if err != nil {t.Fatalf("error -- Create: %v", err)}
+	for i := 1; i <= 5; i++ {
+		err = db.AddEntry(fmt.Sprintf("user:%d", i), fmt.Sprintf("name%d", i))
+		if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+	}
+	err = db.AddEntry("group:1", "admins")
+	if err != nil {t.Fatalf("error -- AddEntry: %v", err)}
+
+	ts = httptest.NewServer(New(cat))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, method, url, body string) (code int, resp string) {
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {t.Fatalf("error -- NewRequest: %v", err)}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {t.Fatalf("error -- %s %s: %v", method, url, err)}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {t.Fatalf("error -- read body: %v", err)}
+	return res.StatusCode, string(data)
+}
+
+func TestKeys(t *testing.T) {
+
+	ts := initServer(t)
+	keyUrl := ts.URL + "/tables/users/keys/"
+
+	code, body := do(t, "GET", keyUrl + "user:1", "")
+	if code != http.StatusOK || body != "name1" {t.Errorf("error -- GET: %d %q", code, body)}
+
+	code, _ = do(t, "PUT", keyUrl + "dir/a b", "value with / and space")
+	if code != http.StatusNoContent {t.Errorf("error -- PUT: %d", code)}
+	code, body = do(t, "GET", keyUrl + url.PathEscape("dir/a b"), "")
+	if code != http.StatusOK || body != "value with / and space" {t.Errorf("error -- GET after PUT: %d %q", code, body)}
+
+	code, _ = do(t, "HEAD", keyUrl + "user:2", "")
+	if code != http.StatusOK {t.Errorf("error -- HEAD existing key: %d", code)}
+
+	code, _ = do(t, "DELETE", keyUrl + "user:2", "")
+	if code != http.StatusNoContent {t.Errorf("error -- DELETE: %d", code)}
+	code, _ = do(t, "HEAD", keyUrl + "user:2", "")
+	if code != http.StatusNotFound {t.Errorf("error -- HEAD deleted key: %d", code)}
+	code, body = do(t, "GET", keyUrl + "user:2", "")
+	if code != http.StatusNotFound || !strings.Contains(body, `"error"`) {t.Errorf("error -- GET deleted key: %d %s", code, body)}
+
+	code, _ = do(t, "PUT", keyUrl, "x")
+	if code != http.StatusBadRequest {t.Errorf("error -- PUT empty key: %d", code)}
+	code, _ = do(t, "GET", ts.URL + "/tables/nosuch/keys/a", "")
+	if code != http.StatusNotFound {t.Errorf("error -- GET unknown table: %d", code)}
+
+	code, body = do(t, "GET", ts.URL + "/tables", "")
+	if code != http.StatusOK || strings.TrimSpace(body) != `{"tables":["users"]}` {t.Errorf("error -- GET tables: %d %s", code, body)}
+}
+
+func TestScanPages(t *testing.T) {
+
+	ts := initServer(t)
+
+	tests := []struct {
+		query string
+		pages string
+	}{
+		{"prefix=user:&limit=2", "[user:1 user:2] [user:3 user:4] [user:5]"},
+		{"prefix=user:&limit=2&reverse=true", "[user:5 user:4] [user:3 user:2] [user:1]"},
+		{"limit=10", "[group:1 user:1 user:2 user:3 user:4 user:5]"},
+		{"prefix=user:&limit=5", "[user:1 user:2 user:3 user:4 user:5]"},
+		{"prefix=x", "[]"},
+	}
+
+	for _, tc := range tests {
+		pages := []string{}
+		cursor := ""
+		for n := 0; n < 10; n++ {
+			query := tc.query
+			if len(cursor) > 0 {query += "&cursor=" + cursor}
+			code, body := do(t, "GET", ts.URL + "/tables/users/scan?" + query, "")
+			if code != http.StatusOK {t.Fatalf("error -- scan %s: %d %s", query, code, body)}
+			var page ScanPage
+			err := json.Unmarshal([]byte(body), &page)
+			if err != nil {t.Fatalf("error -- scan %s: %v", query, err)}
+			keys := []string{}
+			for _, ent := range page.Entries {
+				keys = append(keys, ent.Key)
+				if ent.Key != "group:1" && ent.Val != "name" + strings.TrimPrefix(ent.Key, "user:") {t.Errorf("error -- scan value of %s: %s", ent.Key, ent.Val)}
+			}
+			pages = append(pages, fmt.Sprint(keys))
+			cursor = page.Cursor
+			if len(cursor) == 0 {break}
+		}
+		if strings.Join(pages, " ") != tc.pages {t.Errorf("error -- scan %s: %v expected %s", tc.query, pages, tc.pages)}
+	}
+
+	for _, query := range []string{"limit=0", "reverse=maybe", "cursor=!!", "prefix=user:&cursor=Z3JvdXA6MQ"} {
+		code, _ := do(t, "GET", ts.URL + "/tables/users/scan?" + query, "")
+		if code != http.StatusBadRequest {t.Errorf("error -- scan %s: %d expected 400", query, code)}
+	}
+}
+
+func TestBatch(t *testing.T) {
+
+	ts := initServer(t)
+	batchUrl := ts.URL + "/tables/users/batch"
+
+	code, body := do(t, "POST", batchUrl, `{"ops":[{"op":"put","key":"user:6","value":"name6"},{"op":"delete","key":"user:1"},{"op":"put","key64":"/w==","value64":"AAE="}]}`)
+	if code != http.StatusOK || strings.TrimSpace(body) != `{"puts":2,"deletes":1}` {t.Errorf("error -- batch: %d %s", code, body)}
+
+	// an invalid op rolls back the whole batch
+	code, _ = do(t, "POST", batchUrl, `{"ops":[{"op":"put","key":"user:7","value":"x"},{"op":"frob","key":"user:2"}]}`)
+	if code != http.StatusBadRequest {t.Errorf("error -- batch with invalid op: %d", code)}
+	code, _ = do(t, "POST", batchUrl, `{"ops":[{"op":"put","key":"","value":"x"}]}`)
+	if code != http.StatusBadRequest {t.Errorf("error -- batch with empty key: %d", code)}
+	code, _ = do(t, "POST", batchUrl, `{"ops":`)
+	if code != http.StatusBadRequest {t.Errorf("error -- batch with bad json: %d", code)}
+
+	code, body = do(t, "POST", batchUrl + "/get", `{"keys":["user:1","user:6","user:7","ÿ"]}`)
+	if code != http.StatusOK {t.Fatalf("error -- batch get: %d %s", code, body)}
+	var resp BatchGetResp
+	err := json.Unmarshal([]byte(body), &resp)
+	if err != nil {t.Fatalf("error -- batch get: %v", err)}
+	if len(resp.Entries) != 1 || resp.Entries[0].Key != "user:6" || resp.Entries[0].Val != "name6" {t.Errorf("error -- batch get entries: %+v", resp.Entries)}
+	if fmt.Sprint(resp.Missing) != "[user:1 user:7 ÿ]" {t.Errorf("error -- batch get missing: %v", resp.Missing)}
+
+	code, body = do(t, "GET", ts.URL + "/tables/users/keys/%FF", "")
+	if code != http.StatusOK || body != "\x00\x01" {t.Errorf("error -- GET binary key: %d %q", code, body)}
+}
+
+func TestBodySize(t *testing.T) {
+
+	cat, err := lotusLib.OpenCatalog(t.TempDir(), false)
+	if err != nil {t.Fatalf("error -- OpenCatalog: %v", err)}
+	defer cat.Close()
+	_, err = cat.Create("small", "")
+	if err != nil {t.Fatalf("error -- Create: %v", err)}
+
+	srv := New(cat)
+	srv.MaxBodySize = 4
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("PUT", "/tables/small/keys/a", strings.NewReader("too long")))
+	if rec.Code != http.StatusRequestEntityTooLarge {t.Errorf("error -- PUT larger than MaxBodySize: %d", rec.Code)}
+}
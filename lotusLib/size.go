@@ -0,0 +1,136 @@
+// size
+// byte sizes with units for the yaml config
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes. It is written as a number with an optional unit:
+// K, M, G, T (or KB, MB, GB, TB) are decimal units of 1000,
+// Ki, Mi, Gi, Ti (or KiB, MiB, GiB, TiB) are binary units of 1024.
+// Units are not case sensitive, e.g. "64MiB", "1gb", "512K", "1.5GiB", "4096".
+type Size uint64
+
+type sizeUnit struct {
+	name string
+	mult uint64
+}
+
+// sizeUnits lists the binary units first, so that String prefers them for equal numbers.
+var sizeUnits = []sizeUnit{
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"TB", 1000 * 1000 * 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"MB", 1000 * 1000},
+	{"KB", 1000},
+}
+
+// ParseSize parses a size with an optional unit. The number is decimal and may have a
+// fraction, e.g. 1.5GiB; exponents such as 1e3 are rejected.
+func ParseSize(str string) (sz Size, err error){
+
+	s := strings.TrimSpace(str)
+	if len(s) == 0 {return 0, fmt.Errorf("empty size")}
+
+	idx := len(s)
+	for idx > 0 && (s[idx-1] < '0' || s[idx-1] > '9') {idx--}
+	numStr := strings.TrimSpace(s[:idx])
+	unitStr := strings.ToLower(strings.TrimSpace(s[idx:]))
+
+	var mult uint64
+	switch strings.TrimSuffix(unitStr, "b") {
+	case "":
+		mult = 1
+	case "k":
+		mult = 1000
+	case "m":
+		mult = 1000 * 1000
+	case "g":
+		mult = 1000 * 1000 * 1000
+	case "t":
+		mult = 1000 * 1000 * 1000 * 1000
+	case "ki":
+		mult = 1 << 10
+	case "mi":
+		mult = 1 << 20
+	case "gi":
+		mult = 1 << 30
+	case "ti":
+		mult = 1 << 40
+	default:
+		return 0, fmt.Errorf("size %q: unknown unit %q", str, s[idx:])
+	}
+
+	if !strings.Contains(numStr, ".") {
+		num, err := strconv.ParseUint(numStr, 10, 64)
+		if err != nil {return 0, fmt.Errorf("size %q: invalid number", str)}
+		if num > math.MaxUint64 / mult {return 0, fmt.Errorf("size %q: overflow", str)}
+		return Size(num * mult), nil
+	}
+
+	// ParseFloat also accepts exponents, hex and inf, a size is only digits with a decimal point
+	if strings.Trim(numStr, "0123456789.") != "" {return 0, fmt.Errorf("size %q: invalid number", str)}
+	fnum, err := strconv.ParseFloat(numStr, 64)
+	if err != nil || fnum < 0 {return 0, fmt.Errorf("size %q: invalid number", str)}
+	fsz := fnum * float64(mult)
+	if fsz >= math.MaxUint64 {return 0, fmt.Errorf("size %q: overflow", str)}
+	if fsz != math.Trunc(fsz) {return 0, fmt.Errorf("size %q: not a whole number of bytes", str)}
+	return Size(fsz), nil
+}
+
+// String writes the size with the unit that divides it exactly and gives the smallest number.
+func (sz Size) String() string {
+
+	num := uint64(sz)
+	unit := ""
+	if sz == 0 {return "0"}
+	for _, u := range sizeUnits {
+		if uint64(sz) % u.mult == 0 && uint64(sz) / u.mult < num {
+			num = uint64(sz) / u.mult
+			unit = u.name
+		}
+	}
+	return strconv.FormatUint(num, 10) + unit
+}
+
+func (sz Size) MarshalText() ([]byte, error) {
+	return []byte(sz.String()), nil
+}
+
+func (sz *Size) UnmarshalText(text []byte) (err error) {
+
+	val, err := ParseSize(string(text))
+	if err != nil {return err}
+	*sz = val
+	return nil
+}
+
+// Uint32 converts the size for a uint32 option; field names the option in the error.
+func (sz Size) Uint32(field string) (val uint32, err error) {
+
+	if uint64(sz) > math.MaxUint32 {
+		return 0, &ConfigError{Field: field, Reason: fmt.Sprintf("%s exceeds the maximum of %s", sz, Size(math.MaxUint32))}
+	}
+	return uint32(sz), nil
+}
+
+// Int64 converts the size for an int64 option; field names the option in the error.
+func (sz Size) Int64(field string) (val int64, err error) {
+
+	if uint64(sz) > math.MaxInt64 {
+		return 0, &ConfigError{Field: field, Reason: fmt.Sprintf("%s exceeds the maximum of %s", sz, Size(math.MaxInt64))}
+	}
+	return int64(sz), nil
+}
@@ -0,0 +1,108 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+
+	tests := []struct {
+		str string
+		sz Size
+	}{
+		{"0", 0},
+		{"4096", 4096},
+		{"512K", 512000},
+		{"512KB", 512000},
+		{"512KiB", 512 << 10},
+		{"64MB", 64000000},
+		{"64MiB", 64 << 20},
+		{"64 mib", 64 << 20},
+		{"1GiB", 1 << 30},
+		{"1gb", 1000000000},
+		{"1.5GiB", 3 << 29},
+		{"2Ti", 2 << 40},
+		{"10B", 10},
+	}
+
+	for _, tc := range tests {
+		sz, err := ParseSize(tc.str)
+		if err != nil {t.Errorf("error -- ParseSize(%q): %v", tc.str, err); continue}
+		if sz != tc.sz {t.Errorf("error -- ParseSize(%q): %d expected %d", tc.str, sz, tc.sz)}
+	}
+
+	for _, str := range []string{"", "MB", "-1", "12XB", "1.5", "0.0001K", "20000000TiB", "1e3", "1.5e3", "1.5E3KB", "0x1.8p1"} {
+		sz, err := ParseSize(str)
+		if err == nil {t.Errorf("error -- ParseSize(%q) accepted: %d", str, sz)}
+	}
+}
+
+func TestSizeString(t *testing.T) {
+
+	tests := []struct {
+		sz Size
+		str string
+	}{
+		{0, "0"},
+		{1000, "1KB"},
+		{1024, "1KiB"},
+		{64 << 20, "64MiB"},
+		{1 << 30, "1GiB"},
+		{3 << 29, "1536MiB"},
+		{64000000, "64MB"},
+		{1234, "1234"},
+	}
+
+	for _, tc := range tests {
+		if tc.sz.String() != tc.str {t.Errorf("error -- Size(%d).String(): %s expected %s", tc.sz, tc.sz.String(), tc.str)}
+		sz, err := ParseSize(tc.str)
+		if err != nil || sz != tc.sz {t.Errorf("error -- ParseSize(%q): %d %v expected %d", tc.str, sz, err, tc.sz)}
+	}
+}
+
+func TestSizeOverflow(t *testing.T) {
+
+	_, err := Size(4 << 30).Uint32("MemoryTableSize")
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- Uint32 overflow: %v is not ErrConfig", err)}
+
+	val, err := Size(4 << 30 - 1).Uint32("MemoryTableSize")
+	if err != nil || val != 4 << 30 - 1 {t.Errorf("error -- Uint32: %d %v", val, err)}
+
+	val64, err := Size(1 << 40).Int64("VLogSize")
+	if err != nil || val64 != 1 << 40 {t.Errorf("error -- Int64: %d %v", val64, err)}
+
+	_, err = Size(1 << 63).Int64("VLogSize")
+	if !errors.Is(err, ErrConfig) {t.Errorf("error -- Int64 overflow: %v is not ErrConfig", err)}
+}
+
+func TestSizeYaml(t *testing.T) {
+
+	dirPath := t.TempDir()
+	db, err := InitDb(dirPath, "SizeDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	db.Opt.MemtableSize = 64 << 20
+	db.Opt.ValueLogFileSize = 1 << 30
+	err = db.SaveOption("config.yaml")
+	if err != nil {t.Fatalf("error -- SaveOption: %v", err)}
+
+	yamlData, err := os.ReadFile(dirPath + "/config.yaml")
+	if err != nil {t.Fatalf("error -- ReadFile: %v", err)}
+	for _, line := range []string{"MemoryTableSize: 64MiB", "VLogSize: 1GiB"} {
+		if !strings.Contains(string(yamlData), line) {t.Errorf("error -- config.yaml does not contain %q:\n%s", line, yamlData)}
+	}
+
+	err = os.WriteFile(dirPath + "/config.yaml", []byte("MemoryTableSize: 32MB\nBlockCache: 1GiB\nSyncSize: 512K\nVLogSize: 2GiB\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	err = db.LoadOption("config.yaml")
+	if err != nil {t.Fatalf("error -- LoadOption: %v", err)}
+	if db.Opt.MemtableSize != 32000000 {t.Errorf("error -- MemtableSize: %d", db.Opt.MemtableSize)}
+	if db.Opt.BlockCache != 1 << 30 {t.Errorf("error -- BlockCache: %d", db.Opt.BlockCache)}
+	if db.Opt.BytesPerSync != 512000 {t.Errorf("error -- BytesPerSync: %d", db.Opt.BytesPerSync)}
+	if db.Opt.ValueLogFileSize != 2 << 30 {t.Errorf("error -- ValueLogFileSize: %d", db.Opt.ValueLogFileSize)}
+}
@@ -0,0 +1,173 @@
+// validate
+// validation and defaults of the table options
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+// ConfigErrors lists every invalid field of a config.
+// It matches ErrConfig with errors.Is and each *ConfigError with errors.As.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+
+	strList := make([]string, len(e))
+	for i, cerr := range e {
+		strList[i] = cerr.Error()
+	}
+	return strings.Join(strList, "; ")
+}
+
+func (e ConfigErrors) Is(target error) bool {
+	return target == ErrConfig
+}
+
+func (e ConfigErrors) Unwrap() []error {
+
+	errList := make([]error, len(e))
+	for i, cerr := range e {
+		errList[i] = cerr
+	}
+	return errList
+}
+
+func (e *ConfigErrors) add(field, reason string) {
+	*e = append(*e, &ConfigError{Field: field, Reason: reason})
+}
+
+// addErr adds a conversion error; errors that are not a *ConfigError are reported for field.
+func (e *ConfigErrors) addErr(field string, err error) {
+
+	if err == nil {return}
+	var cerr *ConfigError
+	if errors.As(err, &cerr) {
+		*e = append(*e, cerr)
+		return
+	}
+	*e = append(*e, &ConfigError{Field: field, Reason: "invalid value", Err: err})
+}
+
+// merge adds the errors of other for fields that are not reported yet.
+func (e *ConfigErrors) merge(other error) {
+
+	var errs ConfigErrors
+	if !errors.As(other, &errs) {
+		e.addErr("", other)
+		return
+	}
+	for _, cerr := range errs {
+		if !e.has(cerr.Field) {*e = append(*e, cerr)}
+	}
+}
+
+func (e ConfigErrors) has(field string) bool {
+	for _, cerr := range e {
+		if cerr.Field == field {return true}
+	}
+	return false
+}
+
+// err returns nil for an empty list, so that the result can be compared with nil.
+func (e ConfigErrors) err() error {
+	if len(e) == 0 {return nil}
+	return e
+}
+
+// ValidateOpts checks the options of the table.
+// It returns ConfigErrors with every invalid field by its yaml name, or nil.
+// ValidateOpts does not change the options; see ApplyDefaults.
+func (dbpt *DBObj) ValidateOpts() error {
+
+	var errs ConfigErrors
+	opt := (*dbpt).Opt
+
+	if len(opt.DirPath) == 0 {
+		errs.add("DirPath", "the database directory path cannot be empty")
+	}
+	if opt.MemtableSize == 0 {
+		errs.add("MemoryTableSize", "must be larger than 0")
+	}
+	if opt.MemtableNums <= 0 {
+		errs.add("MemTableNumber", fmt.Sprintf("must be larger than 0, is %d", opt.MemtableNums))
+	}
+	if opt.PartitionNum <= 0 {
+		errs.add("Partitions", fmt.Sprintf("must be larger than 0, is %d", opt.PartitionNum))
+	}
+	if opt.KeyHashFunction == nil {
+		errs.add("KeyHashFunction", "no hash function")
+	} else if len(dbpt.hashNam) > 0 {
+		_, err := HashFunc(dbpt.hashNam)
+		if err != nil {errs.addErr("KeyHashFunction", err)}
+	}
+	if opt.ValueLogFileSize <= 0 {
+		errs.add("VLogSize", fmt.Sprintf("must be larger than 0, is %d", opt.ValueLogFileSize))
+	}
+	_, err := IndexTypeName(opt.IndexType)
+	if err != nil {
+		errs.add("IndexType", fmt.Sprintf("unknown index type %d", opt.IndexType))
+	}
+	if opt.CompactBatchCount <= 0 {
+		errs.add("CompactBatchCount", fmt.Sprintf("must be larger than 0, is %d", opt.CompactBatchCount))
+	}
+	if opt.WaitMemSpaceTimeout <= 0 {
+		errs.add("FlushWaitTime", fmt.Sprintf("must be larger than 0, is %s", opt.WaitMemSpaceTimeout))
+	}
+
+	return errs.err()
+}
+
+// ApplyDefaults sets every unset option of the table to the lotusdb default.
+func (dbpt *DBObj) ApplyDefaults() {
+
+	opt := &dbpt.Opt
+	def := lotusdb.DefaultOptions
+
+	if len(opt.DirPath) == 0 && len(dbpt.DirPath) > 0 {
+		opt.DirPath = dbpt.DirPath + "/" + dbpt.TabNam
+		dbpt.logDefault("DirPath", opt.DirPath)
+	}
+	if opt.MemtableSize == 0 {
+		opt.MemtableSize = def.MemtableSize
+		dbpt.logDefault("MemoryTableSize", Size(opt.MemtableSize).String())
+	}
+	if opt.MemtableNums <= 0 {
+		opt.MemtableNums = def.MemtableNums
+		dbpt.logDefault("MemTableNumber", opt.MemtableNums)
+	}
+	if opt.PartitionNum <= 0 {
+		opt.PartitionNum = def.PartitionNum
+		dbpt.logDefault("Partitions", opt.PartitionNum)
+	}
+	if opt.KeyHashFunction == nil {
+		opt.KeyHashFunction = def.KeyHashFunction
+		dbpt.hashNam = "xxhash"
+		dbpt.logDefault("KeyHashFunction", "xxhash")
+	}
+	if opt.ValueLogFileSize <= 0 {
+		opt.ValueLogFileSize = def.ValueLogFileSize
+		dbpt.logDefault("VLogSize", Size(opt.ValueLogFileSize).String())
+	}
+	if opt.CompactBatchCount <= 0 {
+		opt.CompactBatchCount = def.CompactBatchCount
+		dbpt.logDefault("CompactBatchCount", opt.CompactBatchCount)
+	}
+	if opt.WaitMemSpaceTimeout <= 0 {
+		opt.WaitMemSpaceTimeout = def.WaitMemSpaceTimeout
+		dbpt.logDefault("FlushWaitTime", opt.WaitMemSpaceTimeout)
+	}
+}
+
+func (dbpt *DBObj) logDefault(field string, val any) {
+	if dbpt.Dbg {log.Printf("%s is not set, using default %v\n", field, val)}
+}
@@ -0,0 +1,125 @@
+package lotusLib
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lotusdblabs/lotusdb/v2"
+)
+
+func TestValidateOpts(t *testing.T) {
+
+	db := DBObj{Opt: lotusdb.DefaultOptions}
+	err := db.ValidateOpts()
+	if err != nil {t.Errorf("error -- ValidateOpts of DefaultOptions: %v", err)}
+
+	db.Opt.DirPath = ""
+	db.Opt.MemtableNums = -1
+	db.Opt.PartitionNum = 0
+	db.Opt.IndexType = 9
+	db.Opt.WaitMemSpaceTimeout = 0
+	err = db.ValidateOpts()
+	if !errors.Is(err, ErrConfig) {t.Fatalf("error -- ValidateOpts: %v is not ErrConfig", err)}
+
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {t.Fatalf("error -- ValidateOpts: %v is not ConfigErrors", err)}
+	for _, field := range []string{"DirPath", "MemTableNumber", "Partitions", "IndexType", "FlushWaitTime"} {
+		if !errs.has(field) {t.Errorf("error -- ValidateOpts does not report %s: %v", field, err)}
+	}
+	if len(errs) != 5 {t.Errorf("error -- ValidateOpts reports %d problems expected 5: %v", len(errs), err)}
+
+	// ValidateOpts must not change the options
+	if db.Opt.PartitionNum != 0 || db.Opt.MemtableNums != -1 {t.Errorf("error -- ValidateOpts changed the options: %+v", db.Opt)}
+}
+
+func TestValidate(t *testing.T) {
+
+	db := newDb("testDir", "Tab", false)
+	optObj, err := db.LotusDbOption()
+	if err != nil {t.Fatalf("error -- LotusDbOption: %v", err)}
+	err = optObj.Validate()
+	if err != nil {t.Errorf("error -- Validate of the defaults: %v", err)}
+
+	optObj.DirPath = ""
+	optObj.PartitionNum = 0
+	optObj.IndexType = "tree"
+	err = optObj.Validate()
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {t.Fatalf("error -- Validate: %v is not ConfigErrors", err)}
+	for _, field := range []string{"DirPath", "Partitions", "IndexType"} {
+		if !errs.has(field) {t.Errorf("error -- Validate does not report %s: %v", field, err)}
+	}
+	if len(errs) != 3 {t.Errorf("error -- Validate reports %d problems expected 3: %v", len(errs), err)}
+}
+
+func TestApplyDefaults(t *testing.T) {
+
+	db := DBObj{DirPath: "testDir", TabNam: "Tab"}
+	err := db.ValidateOpts()
+	if err == nil {t.Fatalf("error -- ValidateOpts of empty options succeeded!")}
+
+	db.Opt.PartitionNum = 7
+	db.ApplyDefaults()
+	err = db.ValidateOpts()
+	if err != nil {t.Errorf("error -- ValidateOpts after ApplyDefaults: %v", err)}
+
+	def := lotusdb.DefaultOptions
+	if db.Opt.DirPath != "testDir/Tab" {t.Errorf("error -- DirPath: %s", db.Opt.DirPath)}
+	if db.Opt.MemtableSize != def.MemtableSize {t.Errorf("error -- MemtableSize: %d", db.Opt.MemtableSize)}
+	if db.Opt.MemtableNums != def.MemtableNums {t.Errorf("error -- MemtableNums: %d", db.Opt.MemtableNums)}
+	if db.Opt.ValueLogFileSize != def.ValueLogFileSize {t.Errorf("error -- ValueLogFileSize: %d", db.Opt.ValueLogFileSize)}
+	if db.Opt.WaitMemSpaceTimeout != def.WaitMemSpaceTimeout {t.Errorf("error -- WaitMemSpaceTimeout: %s", db.Opt.WaitMemSpaceTimeout)}
+	if db.Opt.PartitionNum != 7 {t.Errorf("error -- ApplyDefaults overwrote PartitionNum: %d", db.Opt.PartitionNum)}
+}
+
+func TestLoadOptionValidates(t *testing.T) {
+
+	dirPath := t.TempDir()
+	db, err := InitDb(dirPath, "ValDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	yamlStr := "Partitions: -2\nMemTableNumber: 0\nIndexType: skiplist\nKeyHashFunction: md5\nBlockCache: 5GiB\n"
+	err = os.WriteFile(dirPath + "/bad.yaml", []byte(yamlStr), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	opt := db.Opt
+	err = db.LoadOption("bad.yaml")
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {t.Fatalf("error -- LoadOption: %v is not ConfigErrors", err)}
+	for _, field := range []string{"Partitions", "MemTableNumber", "IndexType", "KeyHashFunction", "BlockCache"} {
+		if !errs.has(field) {t.Errorf("error -- LoadOption does not report %s: %v", field, err)}
+	}
+	if len(errs) != 5 {t.Errorf("error -- LoadOption reports %d problems expected 5: %v", len(errs), err)}
+	if db.Opt.PartitionNum != opt.PartitionNum || db.Opt.MemtableNums != opt.MemtableNums {t.Errorf("error -- invalid LoadOption changed the options")}
+}
+
+func TestLoadOptionDecodeErrors(t *testing.T) {
+
+	dirPath := t.TempDir()
+	db, err := InitDb(dirPath, "ValDat", false)
+	if err != nil {t.Fatalf("error -- could not initialise Db: %v", err)}
+	defer db.Close()
+
+	yamlStr := "MemoryTableSize: big\nFlushWaitTime: soon\nPartitions: 0\nBatch:\n  Sync: maybe\nVLogSize: 1GiB\n"
+	err = os.WriteFile(dirPath + "/bad.yaml", []byte(yamlStr), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	opt := db.Opt
+	err = db.LoadOption("bad.yaml")
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {t.Fatalf("error -- LoadOption: %v is not ConfigErrors", err)}
+	for _, field := range []string{"MemoryTableSize", "FlushWaitTime", "Batch.Sync", "Partitions"} {
+		if !errs.has(field) {t.Errorf("error -- LoadOption does not report %s: %v", field, err)}
+	}
+	if len(errs) != 4 {t.Errorf("error -- LoadOption reports %d problems expected 4: %v", len(errs), err)}
+	if db.Opt.ValueLogFileSize != opt.ValueLogFileSize {t.Errorf("error -- LoadOption with invalid keys changed the options")}
+
+	err = os.WriteFile(dirPath + "/badTab.yaml", []byte("TableName: BadDat\n" + yamlStr), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	_, err = OpenFromConfig(dirPath + "/badTab.yaml", false)
+	if !errors.As(err, &errs) || !errs.has("MemoryTableSize") || !errs.has("Partitions") {t.Errorf("error -- OpenFromConfig: %v", err)}
+	if strings.Contains(err.Error(), "\n") {t.Errorf("error -- OpenFromConfig error has several lines: %v", err)}
+}
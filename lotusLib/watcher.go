@@ -0,0 +1,190 @@
+// watcher
+// hot reload of the runtime options from the config file
+// Author: prr azulsoftware
+// Date: 16 Sept 2023
+// copyright (c) 2023 prr, azul software
+//
+
+package lotusLib
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// runtimeKeys are the yaml keys that a Watcher applies to an open table.
+// A change of any other key needs a restart.
+var runtimeKeys = map[string]bool{
+	"Batch.Sync": true,
+	"Batch.ReadOnly": true,
+	"WriteOpt.Sync": true,
+	"WriteOpt.DisableWAL": true,
+	"InterOpt.Prefix": true,
+	"InterOpt.Reverse": true,
+}
+
+// Reload is the result of a config reload.
+type Reload struct {
+	// Applied are the yaml keys of the runtime options that were changed.
+	Applied []string
+	// Restart are the yaml keys that changed but need a restart; they are not applied.
+	Restart []string
+	// Err is the error reading or validating the file; nothing is applied.
+	Err error
+}
+
+// Watcher polls a yaml config file and applies changes of the runtime options,
+// Batch, WriteOpt and InterOpt, to an open table.
+type Watcher struct {
+	FilPath string
+	Interval time.Duration
+	// OnReload is called after every change of the file; Watcher logs the result if OnReload is nil.
+	OnReload func(rl Reload)
+
+	dbp *DBObj
+	mu sync.Mutex
+	modTime time.Time
+	size int64
+	sum [sha256.Size]byte
+	// fileErr is the last error reading the file, reported once
+	fileErr string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// WatchConfig starts a Watcher that polls the config file filPath every interval.
+// The current content of the file is the starting point; only later changes are applied.
+func (dbp *DBObj) WatchConfig (filPath string, interval time.Duration, onReload func(rl Reload)) (w *Watcher, err error){
+
+	if interval <= 0 {return nil, fmt.Errorf("WatchConfig: interval must be larger than 0")}
+
+	w = &Watcher{
+		FilPath: filPath,
+		Interval: interval,
+		OnReload: onReload,
+		dbp: dbp,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	fi, err := os.Stat(filPath)
+	if err != nil {return nil, fmt.Errorf("WatchConfig: %w", err)}
+	yamlData, err := os.ReadFile(filPath)
+	if err != nil {return nil, fmt.Errorf("WatchConfig: %w", err)}
+	w.modTime = fi.ModTime()
+	w.size = fi.Size()
+	w.sum = sha256.Sum256(yamlData)
+
+	go w.run()
+	return w, nil
+}
+
+func (w *Watcher) run() {
+
+	defer close(w.done)
+	tick := time.NewTicker(w.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-tick.C:
+			rl, changed := w.Check()
+			if !changed {continue}
+			if w.OnReload != nil {
+				w.OnReload(rl)
+				continue
+			}
+			if rl.Err != nil {
+				log.Printf("config reload %s: %v\n", w.FilPath, rl.Err)
+				continue
+			}
+			log.Printf("config reload %s: applied %v restart needed %v\n", w.FilPath, rl.Applied, rl.Restart)
+		}
+	}
+}
+
+// Check polls the file once. The file is read if its mtime or size changed,
+// and reloaded if its sha256 changed. changed reports whether the file was reloaded.
+// An error reading the file is reported once, with changed set; the last good state is kept.
+func (w *Watcher) Check() (rl Reload, changed bool) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fi, err := os.Stat(w.FilPath)
+	if err != nil {return w.fileError(err)}
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		w.fileErr = ""
+		return rl, false
+	}
+
+	yamlData, err := os.ReadFile(w.FilPath)
+	if err != nil {return w.fileError(err)}
+	w.fileErr = ""
+	w.modTime = fi.ModTime()
+	w.size = fi.Size()
+
+	sum := sha256.Sum256(yamlData)
+	if sum == w.sum {return rl, false}
+	w.sum = sum
+
+	return w.dbp.reload(w.FilPath, yamlData), true
+}
+
+// fileError reports err unless it was reported by the last Check.
+func (w *Watcher) fileError(err error) (rl Reload, changed bool) {
+
+	if err.Error() == w.fileErr {return rl, false}
+	w.fileErr = err.Error()
+	return Reload{Err: err}, true
+}
+
+// Close stops the Watcher.
+func (w *Watcher) Close() {
+
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+}
+
+// reload applies the runtime options of the yaml data; keys missing in the data keep their value.
+func (dbp *DBObj) reload (filPath string, yamlData []byte) (rl Reload){
+
+	cur, err := dbp.LotusDbOption()
+	if err != nil {return Reload{Err: err}}
+
+	next := cur
+	err = unmarshalOption(filPath, yamlData, &next)
+	if err != nil {return Reload{Err: err}}
+
+	_, batch, write, iterOpt, err := next.Options()
+	if err != nil {return Reload{Err: err}}
+
+	curFields := optFields(&cur)
+	for i, of := range optFields(&next) {
+		// a preset is reported by the keys it changes
+		if of.key == "Preset" {continue}
+		if fmt.Sprint(of.val.Interface()) == fmt.Sprint(curFields[i].val.Interface()) {continue}
+		if runtimeKeys[of.key] {
+			rl.Applied = append(rl.Applied, of.key)
+			continue
+		}
+		rl.Restart = append(rl.Restart, of.key)
+	}
+	if len(rl.Applied) == 0 {return rl}
+
+	dbp.optMu.Lock()
+	dbp.BatchOpt = batch
+	dbp.Write = write
+	dbp.IterOpt = iterOpt
+	dbp.optMu.Unlock()
+	return rl
+}
@@ -0,0 +1,101 @@
+package lotusLib
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "WatchDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+
+	cfgPath := dir + "/config.yaml"
+	err = os.WriteFile(cfgPath, []byte("WriteOpt:\n  Sync: false\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	w, err := db.WatchConfig(cfgPath, time.Hour, nil)
+	if err != nil {t.Fatalf("error -- WatchConfig: %v", err)}
+	defer w.Close()
+
+	_, changed := w.Check()
+	if changed {t.Errorf("error -- Check of an unchanged file reports a change")}
+
+	cfg := "WriteOpt:\n  Sync: true\n  DisableWAL: true\nInterOpt:\n  Prefix: user\nPartitions: 7\n"
+	err = os.WriteFile(cfgPath, []byte(cfg), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	rl, changed := w.Check()
+	if !changed || rl.Err != nil {t.Fatalf("error -- Check: %t %v", changed, rl.Err)}
+	if len(rl.Applied) != 3 {t.Errorf("error -- Applied %v expected 3 keys", rl.Applied)}
+	if len(rl.Restart) != 1 || rl.Restart[0] != "Partitions" {t.Errorf("error -- Restart %v expected Partitions", rl.Restart)}
+	if !db.Write.Sync || !db.Write.DisableWal || string(db.IterOpt.Prefix) != "user" {t.Errorf("error -- runtime options not applied: %+v %+v", db.Write, db.IterOpt)}
+	if db.Opt.PartitionNum == 7 {t.Errorf("error -- Partitions applied to an open table")}
+	if db.ScanOptions().Prefix != "user" {t.Errorf("error -- ScanOptions prefix %q", db.ScanOptions().Prefix)}
+
+	// an invalid file changes nothing
+	err = os.WriteFile(cfgPath, []byte("WriteOpt:\n  Sync: false\nIndexType: tree\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	rl, changed = w.Check()
+	if !changed || rl.Err == nil {t.Errorf("error -- Check of an invalid file: %t %v", changed, rl.Err)}
+	if !db.Write.Sync {t.Errorf("error -- WriteOpt.Sync changed by an invalid file")}
+}
+
+func TestWatcherMissingFile(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "WatchDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+
+	cfgPath := dir + "/config.yaml"
+	err = os.WriteFile(cfgPath, []byte("WriteOpt:\n  Sync: false\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	w, err := db.WatchConfig(cfgPath, time.Hour, nil)
+	if err != nil {t.Fatalf("error -- WatchConfig: %v", err)}
+	defer w.Close()
+
+	err = os.Remove(cfgPath)
+	if err != nil {t.Fatalf("error -- Remove: %v", err)}
+	rl, changed := w.Check()
+	if !changed || !os.IsNotExist(rl.Err) {t.Errorf("error -- Check of a missing file: %t %v", changed, rl.Err)}
+	rl, changed = w.Check()
+	if changed {t.Errorf("error -- second Check of a missing file reports again: %v", rl.Err)}
+
+	err = os.WriteFile(cfgPath, []byte("WriteOpt:\n  Sync: true\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+	rl, changed = w.Check()
+	if !changed || rl.Err != nil || !db.Write.Sync {t.Errorf("error -- Check after the file is back: %t %v %+v", changed, rl.Err, db.Write)}
+}
+
+func TestWatcherPoll(t *testing.T) {
+
+	dir := t.TempDir()
+	db, err := InitDb(dir, "WatchDat", false)
+	if err != nil {t.Fatalf("error -- InitDb: %v", err)}
+	defer db.Close()
+
+	cfgPath := dir + "/config.yaml"
+	err = os.WriteFile(cfgPath, []byte("Batch:\n  Sync: false\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	reloads := make(chan Reload, 4)
+	w, err := db.WatchConfig(cfgPath, 5 * time.Millisecond, func(rl Reload) {reloads <- rl})
+	if err != nil {t.Fatalf("error -- WatchConfig: %v", err)}
+	defer w.Close()
+
+	err = os.WriteFile(cfgPath, []byte("Batch:\n  Sync: true\n  ReadOnly: false\n"), 0666)
+	if err != nil {t.Fatalf("error -- WriteFile: %v", err)}
+
+	select {
+	case rl := <-reloads:
+		if rl.Err != nil || len(rl.Applied) != 1 {t.Errorf("error -- reload: %+v", rl)}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("error -- no reload after the file changed")
+	}
+	if !db.NewBatch().opt.Sync {t.Errorf("error -- Batch.Sync not applied")}
+}